@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"net"
 	"os"
+	"strconv"
 
 	"github.com/sokurenko/go-netstat/netstat"
 )
@@ -14,9 +17,12 @@ var (
 	tcp       = flag.Bool("tcp", false, "display TCP sockets")
 	listening = flag.Bool("lis", false, "display only listening sockets")
 	all       = flag.Bool("all", false, "display both listening and non-listening sockets")
+	closed    = flag.Bool("closed", false, "with -lis, display sockets in the CLOSE state too")
 	resolve   = flag.Bool("res", false, "lookup symbolic names for host addresses")
 	ipv4      = flag.Bool("4", false, "display only IPv4 sockets")
 	ipv6      = flag.Bool("6", false, "display only IPv6 sockets")
+	jsonOut   = flag.Bool("json", false, "print the sockets as a JSON array")
+	showUser  = flag.Bool("user", false, "display the user owning the socket")
 	help      = flag.Bool("help", false, "display this help screen")
 )
 
@@ -25,6 +31,9 @@ const (
 	protoIPv6 = 0x02
 )
 
+// jsonSocks collects the sockets to print when -json is given
+var jsonSocks = []netstat.SockTabEntry{}
+
 func main() {
 	flag.Parse()
 
@@ -44,22 +53,37 @@ func main() {
 		proto = protoIPv4 | protoIPv6
 	}
 
-	if os.Geteuid() != 0 {
-		fmt.Println("Not all processes could be identified, you would have to be root to see it all.")
+	if err := netstat.CheckProcessAccess(); err != nil {
+		msg := "Not all processes could be identified, you would have to be root to see it all."
+		if *jsonOut {
+			fmt.Fprintln(os.Stderr, msg)
+		} else {
+			fmt.Println(msg)
+		}
+	}
+	if !*jsonOut {
+		fmt.Printf("Proto %-23s %-23s %-12s ", "Local Addr", "Foreign Addr", "State")
+		if *showUser {
+			fmt.Printf("%-10s ", "User")
+		}
+		fmt.Printf("%-16s\n", "PID/Program name")
 	}
-	fmt.Printf("Proto %-23s %-23s %-12s %-16s\n", "Local Addr", "Foreign Addr", "State", "PID/Program name")
 
 	if *udp {
 		if proto&protoIPv4 == protoIPv4 {
 			tabs, err := netstat.UDPSocks(netstat.NoopFilter)
 			if err == nil {
 				displaySockInfo("udp", tabs)
+			} else {
+				reportError(err)
 			}
 		}
 		if proto&protoIPv6 == protoIPv6 {
 			tabs, err := netstat.UDP6Socks(netstat.NoopFilter)
 			if err == nil {
 				displaySockInfo("udp6", tabs)
+			} else {
+				reportError(err)
 			}
 		}
 	} else {
@@ -74,7 +98,8 @@ func main() {
 			fn = func(*netstat.SockTabEntry) bool { return true }
 		case *listening:
 			fn = func(s *netstat.SockTabEntry) bool {
-				return s.State == netstat.Listen
+				return s.State == netstat.Listen ||
+					*closed && s.State == netstat.Close
 			}
 		default:
 			fn = func(s *netstat.SockTabEntry) bool {
@@ -86,26 +111,62 @@ func main() {
 			tabs, err := netstat.TCPSocks(fn)
 			if err == nil {
 				displaySockInfo("tcp", tabs)
+			} else {
+				reportError(err)
 			}
 		}
 		if proto&protoIPv6 == protoIPv6 {
 			tabs, err := netstat.TCP6Socks(fn)
 			if err == nil {
 				displaySockInfo("tcp6", tabs)
+			} else {
+				reportError(err)
 			}
 		}
 	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(jsonSocks); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// failed is set once a socket table could not be read
+var failed bool
+
+// reportError prints err unless it is about a table this host lacks, like
+// tcp6 with IPv6 disabled
+func reportError(err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+	failed = true
 }
 
 func displaySockInfo(proto string, s []netstat.SockTabEntry) {
-	lookup := func(skaddr *netstat.SockAddr) string {
+	if *resolve {
+		// The background context never ends, so there is no error; names
+		// that do not resolve simply stay empty
+		_ = netstat.ResolveNames(context.Background(), s, 0)
+	}
+	if *jsonOut {
+		jsonSocks = append(jsonSocks, s...)
+		return
+	}
+
+	lookup := func(skaddr *netstat.SockAddr, host string) string {
 		const IPv4Strlen = 17
 		addr := skaddr.IP.String()
-		if *resolve {
-			names, err := net.LookupAddr(addr)
-			if err == nil && len(names) > 0 {
-				addr = names[0]
-			}
+		if host != "" {
+			addr = host
 		}
 		if len(addr) > IPv4Strlen {
 			addr = addr[:IPv4Strlen]
@@ -118,8 +179,16 @@ func displaySockInfo(proto string, s []netstat.SockTabEntry) {
 		if e.Process != nil {
 			p = e.Process.String()
 		}
-		saddr := lookup(e.LocalAddr)
-		daddr := lookup(e.RemoteAddr)
-		fmt.Printf("%-5s %-23.23s %-23.23s %-12s %-16s\n", proto, saddr, daddr, e.State, p)
+		saddr := lookup(e.LocalAddr, e.LocalHost)
+		daddr := lookup(e.RemoteAddr, e.RemoteHost)
+		fmt.Printf("%-5s %-23.23s %-23.23s %-12s ", proto, saddr, daddr, e.State)
+		if *showUser {
+			name, err := e.Username()
+			if err != nil {
+				name = strconv.FormatUint(uint64(e.UID), 10)
+			}
+			fmt.Printf("%-10.10s ", name)
+		}
+		fmt.Printf("%-16s\n", p)
 	}
 }
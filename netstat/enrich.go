@@ -0,0 +1,133 @@
+package netstat
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+var privateNets []*net.IPNet
+
+func init() {
+	for _, cidr := range []string{
+		"10.0.0.0/8",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"100.64.0.0/10", // carrier-grade NAT
+		"fc00::/7",      // unique local
+	} {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		privateNets = append(privateNets, n)
+	}
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range privateNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// GeoLookupFn returns the country and autonomous system of an IP address,
+// e.g. from a GeoIP database
+type GeoLookupFn func(ip net.IP) (country, asn string)
+
+// EnrichGeo sets RemoteCountry and RemoteASN of every entry from lookup.
+// Remote addresses that are not publicly routable (loopback, private,
+// link-local, unspecified) are skipped.
+func EnrichGeo(entries []SockTabEntry, lookup GeoLookupFn) {
+	for i := range entries {
+		e := &entries[i]
+		if e.RemoteAddr == nil || !isPublicIP(e.RemoteAddr.IP) {
+			continue
+		}
+		e.RemoteCountry, e.RemoteASN = lookup(e.RemoteAddr.IP)
+	}
+}
+
+// resolveHosts sets LocalHost and RemoteHost of all entries. Each distinct
+// address is looked up once, concurrently within the limits of r.
+func resolveHosts(ctx context.Context, entries []SockTabEntry, r *HostResolver) error {
+	names := make(map[string]string)
+	ips := make(map[string]net.IP)
+	for _, e := range entries {
+		for _, a := range []*SockAddr{e.LocalAddr, e.RemoteAddr} {
+			if a != nil && !a.IP.IsUnspecified() {
+				ips[a.IP.String()] = a.IP
+			}
+		}
+	}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for addr, ip := range ips {
+		wg.Add(1)
+		go func(addr string, ip net.IP) {
+			defer wg.Done()
+			if name, ok := r.Resolve(ctx, ip); ok {
+				mu.Lock()
+				names[addr] = name
+				mu.Unlock()
+			}
+		}(addr, ip)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		e := &entries[i]
+		if e.LocalAddr != nil {
+			e.LocalHost = names[e.LocalAddr.IP.String()]
+		}
+		if e.RemoteAddr != nil {
+			e.RemoteHost = names[e.RemoteAddr.IP.String()]
+		}
+	}
+	return nil
+}
+
+// ResolveNames sets LocalHost and RemoteHost of all entries from reverse DNS
+// lookups. Each distinct address is looked up once, with at most concurrency
+// lookups in flight (DefaultResolveConcurrency if not positive), each bounded
+// by DefaultResolveTimeout. Endpoints without a name keep an empty host. An
+// error is returned, and the entries left untouched, if ctx is done before
+// the lookups completed.
+func ResolveNames(ctx context.Context, entries []SockTabEntry, concurrency int) error {
+	return resolveHosts(ctx, entries, &HostResolver{MaxConcurrent: concurrency})
+}
+
+// SocksResolved returns the sockets read by socks that satisfy the accept
+// function, with LocalHost and RemoteHost set from reverse DNS lookups done
+// through r, or through a HostResolver with default settings if r is nil.
+// Endpoints without a name keep an empty host. An error is returned if ctx
+// is done before the lookups completed.
+func SocksResolved(ctx context.Context, socks SocksFn, accept AcceptFn, r *HostResolver) ([]SockTabEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tabs, err := socks(accept)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		r = &HostResolver{}
+	}
+	if err := resolveHosts(ctx, tabs, r); err != nil {
+		return nil, err
+	}
+	return tabs, nil
+}
@@ -0,0 +1,132 @@
+package netstat
+
+// privilegedPortMax is the highest port number that only privileged
+// processes may bind to
+const privilegedPortMax = 1023
+
+// PrivilegedListeners returns the listening sockets bound to a privileged
+// port (below 1024). The owning process of each entry is only known when the
+// caller was allowed to inspect it, which usually requires root.
+func PrivilegedListeners(entries []SockTabEntry) []SockTabEntry {
+	var l []SockTabEntry
+	for _, e := range entries {
+		if e.State == Listen && e.LocalAddr != nil &&
+			e.LocalAddr.Port <= privilegedPortMax {
+			l = append(l, e)
+		}
+	}
+	return l
+}
+
+// FilterByLocalPort returns an AcceptFn accepting sockets whose local port is
+// one of ports
+func FilterByLocalPort(ports ...uint16) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		return s.LocalAddr != nil && hasPort(ports, s.LocalAddr.Port)
+	}
+}
+
+// FilterByRemotePort returns an AcceptFn accepting sockets whose remote port
+// is one of ports
+func FilterByRemotePort(ports ...uint16) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		return s.RemoteAddr != nil && hasPort(ports, s.RemoteAddr.Port)
+	}
+}
+
+func hasPort(ports []uint16, port uint16) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterByState returns an AcceptFn accepting sockets in one of states
+func FilterByState(states ...SkState) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		for _, st := range states {
+			if s.State == st {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// And returns an AcceptFn accepting the sockets accepted by all of fns. The
+// functions are called in order until one of them rejects the socket. With
+// no functions every socket is accepted.
+func And(fns ...AcceptFn) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		for _, fn := range fns {
+			if !fn(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or returns an AcceptFn accepting the sockets accepted by any of fns. The
+// functions are called in order until one of them accepts the socket. With
+// no functions no socket is accepted.
+func Or(fns ...AcceptFn) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		for _, fn := range fns {
+			if fn(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterByPID returns an AcceptFn accepting sockets owned by one of pids.
+// Sockets whose owner is unknown never match. The owning process is looked
+// up after the table has been filtered, so the function rejects every socket
+// when passed to TCPSocks and friends; apply it to their result with Filter.
+func FilterByPID(pids ...int) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		if s.Process == nil {
+			return false
+		}
+		for _, pid := range pids {
+			if s.Process.Pid == pid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterByProcessName returns an AcceptFn accepting sockets owned by a
+// process with one of names. Like FilterByPID it needs the owning process,
+// so apply it with Filter.
+func FilterByProcessName(names ...string) AcceptFn {
+	return func(s *SockTabEntry) bool {
+		if s.Process == nil {
+			return false
+		}
+		for _, name := range names {
+			if s.Process.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Filter returns the entries satisfying the accept function, which may
+// inspect fields such as Process that are only set once a table has been
+// read. The entries are passed to accept in order and are not copied first.
+func Filter(entries []SockTabEntry, accept AcceptFn) []SockTabEntry {
+	var l []SockTabEntry
+	for i := range entries {
+		if accept(&entries[i]) {
+			l = append(l, entries[i])
+		}
+	}
+	return l
+}
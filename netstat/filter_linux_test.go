@@ -0,0 +1,75 @@
+package netstat
+
+import (
+	"strings"
+	"testing"
+)
+
+// filterSample is a tcp table with a listener on 22 and 80, two connections
+// to 22 and one outbound connection to 443
+const filterSample = tcpHeader +
+	"   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000\n" +
+	"   1: 00000000:0050 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000\n" +
+	"   2: 0100000A:0016 0200000A:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000\n" +
+	"   3: 0100000A:0016 0300000A:D432 06 00000000:00000000 00:00000000 00000000     0        0 0 1 0000000000000000\n" +
+	"   4: 0100000A:A001 0400000A:01BB 01 00000000:00000000 00:00000000 00000000  1000        0 1005 1 0000000000000000\n"
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept AcceptFn
+		want   int
+	}{
+		{"local port 22", FilterByLocalPort(22), 3},
+		{"local port 22 or 80", FilterByLocalPort(22, 80), 4},
+		{"remote port 443", FilterByRemotePort(443), 1},
+		{"listening", FilterByState(Listen), 2},
+		{"established or time wait", FilterByState(Established, TimeWait), 3},
+		{"established on 22", And(FilterByLocalPort(22), FilterByState(Established)), 1},
+		{"listening or to 443", Or(FilterByState(Listen), FilterByRemotePort(443)), 3},
+		{"empty And", And(), 5},
+		{"empty Or", Or(), 0},
+		// Owners are unknown while a table is parsed
+		{"pid without process", FilterByPID(0, 10), 0},
+		{"name without process", FilterByProcessName("", "sshd"), 0},
+	}
+	for _, tt := range tests {
+		tab, err := parseSocktab(strings.NewReader(filterSample), "tcp", tt.accept)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tab) != tt.want {
+			t.Errorf("%s: got %d entries, want %d", tt.name, len(tab), tt.want)
+		}
+	}
+}
+
+func TestFilterByProcess(t *testing.T) {
+	tab, err := parseSocktab(strings.NewReader(filterSample), "tcp", NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sshd := &Process{Pid: 10, Name: "sshd"}
+	tab[0].Process = sshd
+	tab[2].Process = sshd
+	tab[4].Process = &Process{Pid: 20, Name: "curl"}
+	// tab[1] and tab[3] have no Process
+
+	tests := []struct {
+		name   string
+		accept AcceptFn
+		want   int
+	}{
+		{"pid 10", FilterByPID(10), 2},
+		{"pid 10 or 20", FilterByPID(10, 20), 3},
+		{"pid 0", FilterByPID(0), 0},
+		{"name sshd", FilterByProcessName("sshd"), 2},
+		{"empty name", FilterByProcessName(""), 0},
+		{"sshd listening", And(FilterByProcessName("sshd"), FilterByState(Listen)), 1},
+	}
+	for _, tt := range tests {
+		if got := Filter(tab, tt.accept); len(got) != tt.want {
+			t.Errorf("%s: got %d entries, want %d", tt.name, len(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package netstat
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// legacyAddrWidth is the width of the address columns of net-tools netstat
+// when not run in wide mode
+const legacyAddrWidth = 23
+
+// legacyAddr formats a like net-tools netstat -n does: port 0 is shown as *,
+// IPv4-mapped addresses keep their ::ffff: prefix and the host part is cut
+// so that the whole address fits the column
+func legacyAddr(a *SockAddr) string {
+	if a == nil {
+		return "*:*"
+	}
+	host := a.IP.String()
+	if len(a.IP) == net.IPv6len && a.IP.To4() != nil {
+		host = "::ffff:" + host
+	}
+	port := "*"
+	if a.Port != 0 {
+		port = strconv.Itoa(int(a.Port))
+	}
+	if n := legacyAddrWidth - 1 - len(port); len(host) > n {
+		host = host[:n]
+	}
+	return host + ":" + port
+}
+
+// legacyState returns the state column of net-tools netstat for e
+func legacyState(e *SockTabEntry) string {
+	switch e.Transport {
+	case "udp", "udp6":
+		switch e.State {
+		case Established:
+			return "ESTABLISHED"
+		case Close:
+			return ""
+		}
+		return "UNKNOWN"
+	}
+	if e.State == Close {
+		return "CLOSE"
+	}
+	return e.State.String()
+}
+
+// WriteLegacyFormat writes entries in the layout of GNU net-tools
+// "netstat -tuan", so that scripts parsing its output keep working: the same
+// two header lines, the Proto, Recv-Q, Send-Q, Local Address, Foreign Address
+// and State columns with identical widths and the same address truncation.
+// Recv-Q and Send-Q are taken from RxQueue and TxQueue.
+func WriteLegacyFormat(w io.Writer, entries []SockTabEntry) error {
+	_, err := io.WriteString(w, "Active Internet connections (servers and established)\n"+
+		"Proto Recv-Q Send-Q Local Address           Foreign Address         State      \n")
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		e := &entries[i]
+		_, err := fmt.Fprintf(w, "%-4s  %6d %6d %-23s %-23s %-11s\n",
+			e.Transport, e.RxQueue, e.TxQueue,
+			legacyAddr(e.LocalAddr), legacyAddr(e.RemoteAddr), legacyState(e))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
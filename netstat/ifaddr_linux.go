@@ -0,0 +1,81 @@
+package netstat
+
+import (
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// ifaFlags is the IFA_FLAGS route attribute, which carries the full 32-bit
+// address flags when they don't fit into ifa_flags
+const ifaFlags = 0x8
+
+// temporaryIPv6Addrs dumps the IPv6 addresses of all interfaces via
+// rtnetlink and returns the ones flagged with IFA_F_TEMPORARY
+func temporaryIPv6Addrs() ([]net.IP, error) {
+	b, err := syscall.NetlinkRIB(syscall.RTM_GETADDR, syscall.AF_INET6)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Header.Type != syscall.RTM_NEWADDR ||
+			len(m.Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+		ifam := (*syscall.IfAddrmsg)(unsafe.Pointer(&m.Data[0]))
+		if ifam.Family != syscall.AF_INET6 {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(m)
+		if err != nil {
+			return nil, err
+		}
+		flags := uint32(ifam.Flags)
+		var ip net.IP
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.IFA_ADDRESS:
+				if len(a.Value) == net.IPv6len {
+					ip = net.IP(a.Value)
+				}
+			case ifaFlags:
+				if len(a.Value) == 4 {
+					flags = *(*uint32)(unsafe.Pointer(&a.Value[0]))
+				}
+			}
+		}
+		if ip != nil && flags&syscall.IFA_F_TEMPORARY != 0 {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// MarkTemporaryAddrs sets LocalAddr.IsTemporary on every entry whose local
+// address is an IPv6 temporary (privacy extension) address of one of the
+// host's interfaces
+func MarkTemporaryAddrs(tab []SockTabEntry) error {
+	ips, err := temporaryIPv6Addrs()
+	if err != nil {
+		return err
+	}
+	for i := range tab {
+		la := tab[i].LocalAddr
+		if la == nil {
+			continue
+		}
+		for _, ip := range ips {
+			if la.IP.Equal(ip) {
+				la.IsTemporary = true
+				break
+			}
+		}
+	}
+	return nil
+}
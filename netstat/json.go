@@ -0,0 +1,99 @@
+package netstat
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONSchemaVersion is the version of the JSON document produced by
+// Snapshot. Fields may be added within a version; the version is bumped only
+// when a change would break existing consumers.
+const JSONSchemaVersion = 1
+
+// Snapshot is a list of sockets that marshals into a versioned JSON document
+// of the form {"version": 1, "sockets": [...]}
+type Snapshot struct {
+	Sockets []SockTabEntry
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	socks := s.Sockets
+	if socks == nil {
+		socks = []SockTabEntry{}
+	}
+	return json.Marshal(struct {
+		Version int            `json:"version"`
+		Sockets []SockTabEntry `json:"sockets"`
+	}{JSONSchemaVersion, socks})
+}
+
+// jsonStates are the names socket states are encoded by in JSON. They are
+// those of String, except that Close, which String leaves blank for display,
+// is named.
+var jsonStates = func() []string {
+	names := make([]string, len(skStates))
+	copy(names, skStates[:])
+	names[Close] = "CLOSE"
+	return names
+}()
+
+// unmarshalName decodes the JSON string b, a name in names or of the form
+// UNKNOWN(n) as printed by String for values without a name, into its index
+func unmarshalName(b []byte, names []string, what string) (uint8, error) {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return 0, err
+	}
+	for i, n := range names {
+		if n != "" && n == name {
+			return uint8(i), nil
+		}
+	}
+	var i uint8
+	if _, err := fmt.Sscanf(name, "UNKNOWN(%d)", &i); err != nil {
+		return 0, fmt.Errorf("gonetstat: unknown %s %q", what, name)
+	}
+	return i, nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the state by its name
+func (s SkState) MarshalJSON() ([]byte, error) {
+	if int(s) < len(jsonStates) {
+		return json.Marshal(jsonStates[s])
+	}
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding a state encoded by
+// MarshalJSON
+func (s *SkState) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	i, err := unmarshalName(b, jsonStates, "socket state")
+	if err != nil {
+		return err
+	}
+	*s = SkState(i)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the type by its name
+func (t SockType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding a type encoded by
+// MarshalJSON
+func (t *SockType) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	i, err := unmarshalName(b, sockTypes[:], "socket type")
+	if err != nil {
+		return err
+	}
+	*t = SockType(i)
+	return nil
+}
@@ -0,0 +1,261 @@
+package netstat
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	netlinkInetDiag  = 4  // NETLINK_INET_DIAG
+	sockDiagByFamily = 20 // SOCK_DIAG_BY_FAMILY
+
+	// diagAllStates selects sockets in any state
+	diagAllStates = 0xffffffff
+)
+
+// inetDiagSockID is struct inet_diag_sockid; ports and addresses are in
+// network byte order
+type inetDiagSockID struct {
+	SPort  [2]byte
+	DPort  [2]byte
+	Src    [16]byte
+	Dst    [16]byte
+	If     uint32
+	Cookie [2]uint32
+}
+
+// inetDiagReqV2 is struct inet_diag_req_v2
+type inetDiagReqV2 struct {
+	Family   uint8
+	Protocol uint8
+	Ext      uint8
+	Pad      uint8
+	States   uint32
+	ID       inetDiagSockID
+}
+
+// inetDiagMsg is struct inet_diag_msg
+type inetDiagMsg struct {
+	Family  uint8
+	State   uint8
+	Timer   uint8
+	Retrans uint8
+	ID      inetDiagSockID
+	Expires uint32
+	RQueue  uint32
+	WQueue  uint32
+	UID     uint32
+	Inode   uint32
+}
+
+type inetDiagRequest struct {
+	Header syscall.NlMsghdr
+	Req    inetDiagReqV2
+}
+
+// diagSocket is a NETLINK_INET_DIAG socket
+type diagSocket struct {
+	fd  int
+	seq uint32
+}
+
+func openDiagSocket() (*diagSocket, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkInetDiag)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	return &diagSocket{fd: fd}, nil
+}
+
+func (s *diagSocket) Close() error {
+	return syscall.Close(s.fd)
+}
+
+// dump requests all sockets of the given family and protocol and calls fn
+// for each of them
+func (s *diagSocket) dump(family, protocol uint8, fn func(*inetDiagMsg)) error {
+	s.seq++
+	req := inetDiagRequest{
+		Header: syscall.NlMsghdr{
+			Len:   uint32(unsafe.Sizeof(inetDiagRequest{})),
+			Type:  sockDiagByFamily,
+			Flags: syscall.NLM_F_REQUEST | syscall.NLM_F_DUMP,
+			Seq:   s.seq,
+		},
+		Req: inetDiagReqV2{
+			Family:   family,
+			Protocol: protocol,
+			States:   diagAllStates,
+		},
+	}
+	b := (*[unsafe.Sizeof(inetDiagRequest{})]byte)(unsafe.Pointer(&req))[:]
+	if err := syscall.Sendto(s.fd, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+
+	buf := make([]byte, 32*os.Getpagesize())
+	for {
+		n, _, err := syscall.Recvfrom(s.fd, buf, 0)
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return os.NewSyscallError("recvfrom", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return err
+		}
+		for i := range msgs {
+			m := &msgs[i]
+			if m.Header.Seq != s.seq {
+				continue
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return nil
+			case syscall.NLMSG_ERROR:
+				if len(m.Data) < 4 {
+					return syscall.EINVAL
+				}
+				errno := *(*int32)(unsafe.Pointer(&m.Data[0]))
+				if errno == 0 {
+					continue
+				}
+				return os.NewSyscallError("sock_diag", syscall.Errno(-errno))
+			case sockDiagByFamily:
+				if len(m.Data) < int(unsafe.Sizeof(inetDiagMsg{})) {
+					return syscall.EINVAL
+				}
+				fn((*inetDiagMsg)(unsafe.Pointer(&m.Data[0])))
+			}
+		}
+	}
+}
+
+func diagAddr(family uint8, addr *[16]byte, port *[2]byte, ifindex uint32) *SockAddr {
+	var ip net.IP
+	if family == syscall.AF_INET {
+		ip = make(net.IP, net.IPv4len)
+	} else {
+		ip = make(net.IP, net.IPv6len)
+	}
+	copy(ip, addr[:])
+	a := &SockAddr{IP: ip, Port: binary.BigEndian.Uint16(port[:])}
+	if ifindex != 0 && family == syscall.AF_INET6 &&
+		(ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+		a.Zone = zoneName(int(ifindex))
+	}
+	return a
+}
+
+// zoneName returns the name of the interface with the given index, or the
+// index itself if there is no such interface
+func zoneName(index int) string {
+	if ifi, err := net.InterfaceByIndex(index); err == nil {
+		return ifi.Name
+	}
+	return strconv.Itoa(index)
+}
+
+func diagToSockTabEntry(m *inetDiagMsg, transport string) SockTabEntry {
+	typ, proto := inetProto(transport)
+	e := SockTabEntry{
+		ino:        strconv.FormatUint(uint64(m.Inode), 10),
+		Transport:  transport,
+		Type:       typ,
+		Protocol:   proto,
+		LocalAddr:  diagAddr(m.Family, &m.ID.Src, &m.ID.SPort, m.ID.If),
+		RemoteAddr: diagAddr(m.Family, &m.ID.Dst, &m.ID.DPort, m.ID.If),
+		State:      SkState(m.State),
+		TxQueue:    uint64(m.WQueue),
+		RxQueue:    uint64(m.RQueue),
+		UID:        m.UID,
+		TimerWhen:  uint64(m.Expires) * UserHZ / 1000, // expires is in ms
+	}
+	// For listeners the dump reports the backlog limit as the write queue,
+	// the /proc tables show zero
+	if e.State == Listen {
+		e.TxQueue = 0
+	}
+	return e
+}
+
+// diagUnsupported reports whether err means the kernel lacks the sock_diag
+// handler for a protocol, e.g. because udp_diag is not loaded
+func diagUnsupported(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EOPNOTSUPP)
+}
+
+// NetlinkSocks returns the sockets of every table in t that satisfy the
+// accept function, like Socks, but reads them from a NETLINK_INET_DIAG
+// (sock_diag) dump instead of parsing the /proc/net text tables, which is
+// considerably faster with many sockets. The entries match those of Socks,
+// except that Ref, Pointer, the TCP timer and congestion columns and the UDP
+// drop counter are not part of the dump and stay zero.
+//
+// If the netlink socket cannot be opened, e.g. under a restrictive seccomp
+// profile, or the kernel lacks the diag module for a protocol, the /proc
+// tables are read instead. Tables that cannot be read are reported in a
+// MultiError along with the sockets of the others, as by Socks.
+func NetlinkSocks(t Transports, accept AcceptFn) ([]SockTabEntry, error) {
+	s, err := openDiagSocket()
+	if err != nil {
+		return Socks(t, accept)
+	}
+	defer s.Close()
+
+	// The dump lists the sockets of the namespace the netlink socket was
+	// created in, which is ours
+	netns, _ := HostNetNsInode()
+	var (
+		tabs []SockTabEntry
+		errs MultiError
+	)
+	for _, ts := range transportSocks {
+		if t&ts.t == 0 {
+			continue
+		}
+		family := uint8(syscall.AF_INET)
+		if strings.HasSuffix(ts.name, "6") {
+			family = syscall.AF_INET6
+		}
+		_, protocol := inetProto(ts.name)
+		var tab []SockTabEntry
+		err := s.dump(family, uint8(protocol), func(m *inetDiagMsg) {
+			e := diagToSockTabEntry(m, ts.name)
+			e.NetNSInode = netns
+			if accept(&e) {
+				tab = append(tab, e)
+			}
+		})
+		if err != nil {
+			if !diagUnsupported(err) {
+				errs = append(errs, err)
+				continue
+			}
+			tab, err = doNetstat(procNetPath(ts.name), parseSocktab, accept)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		} else if len(tab) != 0 {
+			extractProcInfo(tab)
+		}
+		tabs = append(tabs, tab...)
+	}
+	if errs != nil {
+		return tabs, errs
+	}
+	return tabs, nil
+}
@@ -0,0 +1,98 @@
+package netstat
+
+import (
+	"net"
+	"sort"
+	"syscall"
+	"testing"
+)
+
+func TestNetlinkSocksMatchesSocks(t *testing.T) {
+	s, err := openDiagSocket()
+	if err != nil {
+		t.Skipf("NETLINK_SOCK_DIAG unavailable: %v", err)
+	}
+	s.Close()
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	a, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	accept := Or(FilterByLocalPort(port), FilterByRemotePort(port))
+	byKey := func(tab []SockTabEntry) {
+		sort.Slice(tab, func(i, j int) bool { return sockKey(&tab[i]) < sockKey(&tab[j]) })
+	}
+	want, err := Socks(TransportTCP, accept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NetlinkSocks(TransportTCP, accept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The listener and both ends of the connection
+	if len(want) != 3 || len(got) != len(want) {
+		t.Fatalf("got %d sockets from netlink and %d from /proc, want 3", len(got), len(want))
+	}
+	byKey(want)
+	byKey(got)
+	for i := range want {
+		g, w := &got[i], &want[i]
+		if sockKey(g) != sockKey(w) || g.State != w.State || g.UID != w.UID || g.ino != w.ino ||
+			g.Type != w.Type || g.Protocol != w.Protocol || g.NetNSInode != w.NetNSInode ||
+			g.TxQueue != w.TxQueue || g.RxQueue != w.RxQueue {
+			t.Errorf("got %+v from netlink, want %+v", *g, *w)
+		}
+		if (g.Process == nil) != (w.Process == nil) ||
+			g.Process != nil && g.Process.Pid != w.Process.Pid {
+			t.Errorf("%s: got process %v from netlink, want %v", sockKey(g), g.Process, w.Process)
+		}
+	}
+}
+
+func TestDiagAddrZone(t *testing.T) {
+	ifs, err := net.Interfaces()
+	if err != nil || len(ifs) == 0 {
+		t.Skipf("no network interface: %v", err)
+	}
+	ifi := ifs[0]
+	port := [2]byte{0, 22}
+	var linkLocal, global, v4 [16]byte
+	copy(linkLocal[:], net.ParseIP("fe80::1"))
+	copy(global[:], net.ParseIP("2001:db8::1"))
+	copy(v4[:], net.ParseIP("10.0.0.1").To4())
+
+	tests := []struct {
+		family  uint8
+		addr    *[16]byte
+		ifindex uint32
+		want    string
+	}{
+		{syscall.AF_INET6, &linkLocal, uint32(ifi.Index), "fe80::1%" + ifi.Name + ":22"},
+		// An index no interface has is kept as is
+		{syscall.AF_INET6, &linkLocal, 1 << 30, "fe80::1%1073741824:22"},
+		// Unbound sockets have no interface
+		{syscall.AF_INET6, &linkLocal, 0, "fe80::1:22"},
+		// Only link-local addresses are scoped
+		{syscall.AF_INET6, &global, uint32(ifi.Index), "2001:db8::1:22"},
+		{syscall.AF_INET, &v4, uint32(ifi.Index), "10.0.0.1:22"},
+	}
+	for _, tt := range tests {
+		if got := diagAddr(tt.family, tt.addr, &port, tt.ifindex).String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
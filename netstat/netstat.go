@@ -1,51 +1,215 @@
 package netstat
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"os"
+	"strings"
+	"time"
 )
 
+// ErrLimitedProcessInfo is returned by CheckProcessAccess when the owning
+// processes of most sockets cannot be determined
+var ErrLimitedProcessInfo = errors.New("gonetstat: not all processes could be identified")
+
 // SockAddr represents an ip:port pair
 type SockAddr struct {
-	IP   net.IP
-	Port uint16
+	IP   net.IP `json:"ip"`
+	Port uint16 `json:"port"`
+	// IsTemporary is set by MarkTemporaryAddrs when IP is an IPv6
+	// privacy extension (temporary) address of a local interface
+	IsTemporary bool `json:"is_temporary,omitempty"`
+	// Zone is the IPv6 scope of a link-local IP, the name of the interface
+	// (or its index if the name is unknown). It is only known for entries
+	// read by NetlinkSocks, as the /proc tables carry no scope, and is
+	// always empty for other addresses.
+	Zone string `json:"zone,omitempty"`
 }
 
 func (s *SockAddr) String() string {
+	if s.Zone != "" {
+		return fmt.Sprintf("%v%%%s:%d", s.IP, s.Zone, s.Port)
+	}
 	return fmt.Sprintf("%v:%d", s.IP, s.Port)
 }
 
+// Is4in6 reports whether IP is an IPv4-mapped IPv6 address (::ffff:a.b.c.d),
+// as found in the IPv6 tables for sockets talking to IPv4 peers. IPs from the
+// IPv4 tables are 4 bytes long and never mapped. The IP itself is kept as
+// read; net.IP.String, and thus String, already renders a mapped address in
+// dotted-quad form, and IP.To4 yields its IPv4 form for comparisons.
+func (s *SockAddr) Is4in6() bool {
+	return len(s.IP) == net.IPv6len && s.IP.To4() != nil
+}
+
 // SockTabEntry type represents each line of the /proc/net/[tcp|udp]
 type SockTabEntry struct {
-	ino        string
-	LocalAddr  *SockAddr
-	RemoteAddr *SockAddr
-	State      SkState
-	UID        uint32
-	Process    *Process
+	ino string
+	// Transport names the table the entry was read from: tcp, tcp6, udp,
+	// udp6, raw, raw6, unix or sctp
+	Transport string `json:"transport"`
+	// Type is the socket type and Protocol the IP protocol number, e.g. 6
+	// for TCP or 1 for ICMP raw sockets; it is zero for Unix sockets
+	Type       SockType  `json:"type,omitempty"`
+	Protocol   int       `json:"protocol,omitempty"`
+	LocalAddr  *SockAddr `json:"local_addr"`
+	RemoteAddr *SockAddr `json:"remote_addr"`
+	State      SkState   `json:"state"`
+	TxQueue    uint64    `json:"tx_queue"`
+	RxQueue    uint64    `json:"rx_queue"`
+	UID        uint32    `json:"uid"`
+	// NetNSInode is the inode number of the network namespace the socket
+	// belongs to, as listed by lsns. Linux only.
+	NetNSInode uint64 `json:"netns_inode,omitempty"`
+	// TimerWhen is the time until the socket's pending timer (e.g.
+	// retransmit, keepalive or TIME_WAIT) expires in clock ticks, zero if
+	// none is pending. See TimerExpiry.
+	TimerWhen uint64 `json:"timer_when,omitempty"`
+	// RTO and ATO are the retransmission and delayed ACK timeouts in clock
+	// ticks (USER_HZ), SndCwnd and SndSsthresh the congestion window and
+	// slow start threshold in segments. They are only reported for TCP
+	// sockets with full table lines and stay zero otherwise. SndCwnd and
+	// SndSsthresh are also zero for listening sockets, SndSsthresh during
+	// initial slow start.
+	RTO         uint64 `json:"rto,omitempty"`
+	ATO         uint64 `json:"ato,omitempty"`
+	SndCwnd     uint64 `json:"snd_cwnd,omitempty"`
+	SndSsthresh uint64 `json:"snd_ssthresh,omitempty"`
+	// Drops counts the datagrams dropped by a UDP socket, e.g. because its
+	// receive buffer was full
+	Drops uint64 `json:"drops,omitempty"`
+	// Ref and Pointer are the socket's reference count and kernel address
+	// as printed after the inode column of /proc/net/[tcp|udp]. Depending
+	// on kptr_restrict, Pointer may be hashed or zeroed by the kernel.
+	Ref     uint32   `json:"ref"`
+	Pointer uint64   `json:"pointer"`
+	Process *Process `json:"process,omitempty"`
+	// Path is the bound path of a Unix domain socket, prefixed with @ for
+	// abstract sockets
+	Path string `json:"path,omitempty"`
+	// ExtraLocalIPs and ExtraRemoteIPs are the addresses of a multihomed
+	// SCTP endpoint besides the primary one in LocalAddr and RemoteAddr
+	ExtraLocalIPs  []net.IP `json:"extra_local_ips,omitempty"`
+	ExtraRemoteIPs []net.IP `json:"extra_remote_ips,omitempty"`
+	// LocalHost and RemoteHost are the endpoints' host names, filled in by
+	// ResolveNames and SocksResolved
+	LocalHost  string `json:"local_host,omitempty"`
+	RemoteHost string `json:"remote_host,omitempty"`
+	// RemoteCountry and RemoteASN are filled in by EnrichGeo
+	RemoteCountry string `json:"remote_country,omitempty"`
+	RemoteASN     string `json:"remote_asn,omitempty"`
+	// Meta is reserved for callers to attach their own data, e.g. from
+	// within an AcceptFn. The package never reads or sets it.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// UserHZ is the number of clock ticks per second (USER_HZ) the kernel uses
+// for the timer columns of the socket tables. It is 100 on every Linux
+// architecture, independently of the kernel's internal CONFIG_HZ.
+var UserHZ uint64 = 100
+
+// TimerExpiry returns the time until the socket's pending timer expires,
+// converting TimerWhen from clock ticks using UserHZ
+func (e *SockTabEntry) TimerExpiry() time.Duration {
+	return time.Duration(e.TimerWhen) * time.Second / time.Duration(UserHZ)
+}
+
+// IsWildcardListen reports whether the socket listens on all addresses of
+// its family, i.e. is bound to 0.0.0.0 or ::
+func (e *SockTabEntry) IsWildcardListen() bool {
+	return e.State == Listen && e.LocalAddr != nil && e.LocalAddr.IP.IsUnspecified()
+}
+
+// IsLoopback reports whether the socket is bound to a loopback address
+// (127.0.0.0/8, ::1 or an IPv4-mapped 127.x.y.z), and thus only reachable
+// from this host
+func (e *SockTabEntry) IsLoopback() bool {
+	return e.LocalAddr != nil && e.LocalAddr.IP.IsLoopback()
 }
 
 // Process holds the PID and process name to which each socket belongs
 type Process struct {
-	Pid  int
-	Name string
+	Pid  int    `json:"pid"`
+	Name string `json:"name"`
+	// Env holds the environment variables read by LoadEnv
+	Env map[string]string `json:"env,omitempty"`
+	// Cmdline holds the command line arguments read by LoadCmdline
+	Cmdline []string `json:"cmdline,omitempty"`
+	// Exe holds the executable path read by LoadExe
+	Exe string `json:"exe,omitempty"`
+	// ContainerID holds the container id read by LoadContainerID
+	ContainerID string `json:"container_id,omitempty"`
 }
 
 func (p *Process) String() string {
 	return fmt.Sprintf("%d/%s", p.Pid, p.Name)
 }
 
+// SockType is the type of a socket
+type SockType uint8
+
+// Socket types
+const (
+	SockStream SockType = iota + 1
+	SockDgram
+	SockRaw
+	SockSeqPacket
+)
+
+var sockTypes = [...]string{
+	SockStream:    "STREAM",
+	SockDgram:     "DGRAM",
+	SockRaw:       "RAW",
+	SockSeqPacket: "SEQPACKET",
+}
+
+func (t SockType) String() string {
+	if int(t) >= len(sockTypes) || sockTypes[t] == "" {
+		return fmt.Sprintf("UNKNOWN(%d)", t)
+	}
+	return sockTypes[t]
+}
+
+// IP protocol numbers
+const (
+	protoTCP  = 6
+	protoUDP  = 17
+	protoSCTP = 132
+)
+
+// inetProto returns the socket type and IP protocol of the sockets listed in
+// the tcp, tcp6, udp and udp6 tables
+func inetProto(transport string) (SockType, int) {
+	switch transport {
+	case "tcp", "tcp6":
+		return SockStream, protoTCP
+	case "udp", "udp6":
+		return SockDgram, protoUDP
+	}
+	return 0, 0
+}
+
 // SkState type represents socket connection state
 type SkState uint8
 
 func (s SkState) String() string {
+	if int(s) >= len(skStates) {
+		return fmt.Sprintf("UNKNOWN(%d)", s)
+	}
 	return skStates[s]
 }
 
 // AcceptFn is used to filter socket entries. The value returned indicates
-// whether the element is to be appended to the socket list.
+// whether the element is to be appended to the socket list. The function may
+// also modify the entry (e.g. fill in Meta); accepted entries are appended
+// with those modifications in place, in the same pass over the table.
 type AcceptFn func(*SockTabEntry) bool
 
+// SocksFn is the signature shared by TCPSocks, TCP6Socks, UDPSocks and
+// UDP6Socks, so helpers can be handed the socket table they should read
+type SocksFn func(accept AcceptFn) ([]SockTabEntry, error)
+
 // NoopFilter - a test function returning true for all elements
 func NoopFilter(*SockTabEntry) bool { return true }
 
@@ -72,3 +236,156 @@ func UDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 func UDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	return osUDP6Socks(accept)
 }
+
+// Transports is a set of socket tables, combined with bitwise or, e.g.
+// TransportTCP | TransportTCP6
+type Transports uint
+
+// Socket tables that can be combined into Transports
+const (
+	TransportTCP Transports = 1 << iota
+	TransportTCP6
+	TransportUDP
+	TransportUDP6
+
+	TransportAll = TransportTCP | TransportTCP6 | TransportUDP | TransportUDP6
+)
+
+// transportSocks lists the tables selected by each of Transports, with the
+// Transport of their entries
+var transportSocks = []struct {
+	t     Transports
+	name  string
+	socks SocksFn
+}{
+	{TransportTCP, "tcp", TCPSocks},
+	{TransportTCP6, "tcp6", TCP6Socks},
+	{TransportUDP, "udp", UDPSocks},
+	{TransportUDP6, "udp6", UDP6Socks},
+}
+
+// MultiError holds the errors of the socket tables that could not be read,
+// in table order. errors.Is and errors.As match any of them.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the errors matches target
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors that matches target
+func (m MultiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Socks returns the sockets of every table in t that satisfy the accept
+// function, in the order tcp, tcp6, udp, udp6. Each entry's Transport field
+// tells which table it comes from. A table that cannot be read does not stop
+// the others: their sockets are returned along with a MultiError holding the
+// failures, so an empty result with a nil error really means no sockets. Use
+// errors.Is(err, os.ErrNotExist) to spot tables missing on this host.
+func Socks(t Transports, accept AcceptFn) ([]SockTabEntry, error) {
+	var (
+		tabs []SockTabEntry
+		errs MultiError
+	)
+	for _, ts := range transportSocks {
+		if t&ts.t == 0 {
+			continue
+		}
+		tab, err := ts.socks(accept)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		tabs = append(tabs, tab...)
+	}
+	if errs != nil {
+		return tabs, errs
+	}
+	return tabs, nil
+}
+
+// SSCommand renders the ss(8) command line listing the same tables as t,
+// e.g. "ss -tan -4" for TransportTCP. A family flag is only added when every
+// selected table is of the same address family. Filtering done by an
+// AcceptFn cannot be expressed and is not part of the command. An empty set
+// renders as "", as ss(8) has no way to list no tables.
+func (t Transports) SSCommand() string {
+	if t&TransportAll == 0 {
+		return ""
+	}
+	flags := ""
+	if t&(TransportTCP|TransportTCP6) != 0 {
+		flags += "t"
+	}
+	if t&(TransportUDP|TransportUDP6) != 0 {
+		flags += "u"
+	}
+	cmd := "ss -" + flags + "an"
+	v4 := t&(TransportTCP|TransportUDP) != 0
+	v6 := t&(TransportTCP6|TransportUDP6) != 0
+	switch {
+	case v4 && !v6:
+		cmd += " -4"
+	case v6 && !v4:
+		cmd += " -6"
+	}
+	return cmd
+}
+
+// Listeners returns the listening TCP sockets and, if udp is set, the UDP
+// sockets waiting for datagrams, i.e. those not connected to a peer. Only
+// listeners are passed on for process lookup, which keeps this cheaper than
+// filtering the full tables afterwards. IPv6 tables missing on this host are
+// skipped.
+func Listeners(udp bool) ([]SockTabEntry, error) {
+	tcpFn := func(s *SockTabEntry) bool { return s.State == Listen }
+	udpFn := func(s *SockTabEntry) bool {
+		return s.State == Close && s.RemoteAddr.Port == 0
+	}
+	type table struct {
+		socks  SocksFn
+		accept AcceptFn
+		v6     bool
+	}
+	tables := []table{
+		{TCPSocks, tcpFn, false},
+		{TCP6Socks, tcpFn, true},
+	}
+	if udp {
+		tables = append(tables, table{UDPSocks, udpFn, false},
+			table{UDP6Socks, udpFn, true})
+	}
+
+	var l []SockTabEntry
+	for _, t := range tables {
+		tab, err := t.socks(t.accept)
+		if err != nil {
+			if t.v6 && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		l = append(l, tab...)
+	}
+	return l, nil
+}
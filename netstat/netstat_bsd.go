@@ -0,0 +1,251 @@
+//go:build darwin || freebsd
+// +build darwin freebsd
+
+package netstat
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Socket states, as the TCPS_* values of netinet/tcp_fsm.h
+const (
+	Close       SkState = 0x00
+	Listen              = 0x01
+	SynSent             = 0x02
+	SynRecv             = 0x03
+	Established         = 0x04
+	CloseWait           = 0x05
+	FinWait1            = 0x06
+	Closing             = 0x07
+	LastAck             = 0x08
+	FinWait2            = 0x09
+	TimeWait            = 0x0a
+)
+
+var skStates = [...]string{
+	"", // CLOSE
+	"LISTEN",
+	"SYN_SENT",
+	"SYN_RECV",
+	"ESTABLISHED",
+	"CLOSE_WAIT",
+	"FIN_WAIT1",
+	"CLOSING",
+	"LAST_ACK",
+	"FIN_WAIT2",
+	"TIME_WAIT",
+}
+
+// Address family flags of inp_vflag, INP_IPV4 and INP_IPV6
+const (
+	inpIPv4 = 0x1
+	inpIPv6 = 0x2
+)
+
+// ctlMaxName is the maximum number of components of a sysctl MIB
+const ctlMaxName = 24
+
+func sysctl(mib []int32, old *byte, oldlen *uintptr, new *byte, newlen uintptr) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS___SYSCTL,
+		uintptr(unsafe.Pointer(&mib[0])), uintptr(len(mib)),
+		uintptr(unsafe.Pointer(old)), uintptr(unsafe.Pointer(oldlen)),
+		uintptr(unsafe.Pointer(new)), newlen)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// sysctlMIB translates the name of a sysctl into its MIB
+func sysctlMIB(name string) ([]int32, error) {
+	var mib [ctlMaxName]int32
+	n := uintptr(len(mib) * 4)
+	p := []byte(name)
+	// {CTL_SYSCTL, CTL_SYSCTL_NAME2OID}
+	err := sysctl([]int32{0, 3}, (*byte)(unsafe.Pointer(&mib[0])), &n, &p[0], uintptr(len(p)))
+	if err != nil {
+		return nil, err
+	}
+	return mib[:n/4], nil
+}
+
+// sysctlRaw returns the value of the sysctl identified by mib. Unlike
+// syscall.Sysctl it keeps a trailing NUL byte, which is part of binary values.
+func sysctlRaw(mib []int32) ([]byte, error) {
+	for {
+		var n uintptr
+		if err := sysctl(mib, nil, &n, nil, 0); err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		b := make([]byte, n)
+		err := sysctl(mib, &b[0], &n, nil, 0)
+		if err == syscall.ENOMEM {
+			// The value grew since its size was queried, e.g. a socket
+			// was opened
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+
+// sysctlByName returns the value of the sysctl name
+func sysctlByName(name string) ([]byte, error) {
+	mib, err := sysctlMIB(name)
+	if err == nil {
+		var b []byte
+		if b, err = sysctlRaw(mib); err == nil {
+			return b, nil
+		}
+	}
+	return nil, os.NewSyscallError("sysctl "+name, err)
+}
+
+// roundup8 rounds n up to a multiple of 8, the alignment of the structures
+// listed by the pcblist sysctls
+func roundup8(n int) int {
+	return (n + 7) &^ 7
+}
+
+// pcb holds the fields of an Internet protocol control block, as listed by
+// the pcblist sysctls, that make up a SockTabEntry
+type pcb struct {
+	so           uint64 // kernel address of the socket
+	family       int
+	vflag        uint8
+	lport, fport uint16
+	// in_dependaddr: an IPv6 address, or an IPv4 one in the last 4 bytes
+	laddr, faddr [16]byte
+	state        SkState
+	uid          uint32
+	rxq, txq     uint64
+	pid          int
+}
+
+// ip returns the address a of p, in the form of the tcp/udp table unless v6
+func (p *pcb) ip(a *[16]byte, v6 bool) net.IP {
+	if p.vflag&inpIPv6 != 0 {
+		ip := make(net.IP, net.IPv6len)
+		copy(ip, a[:])
+		return ip
+	}
+	// An IPv6 socket talking to an IPv4-mapped address is switched over
+	// to IPv4
+	ip := net.IPv4(a[12], a[13], a[14], a[15])
+	if !v6 {
+		ip = ip.To4()
+	}
+	return ip
+}
+
+// procName returns the command name of process pid from its kinfo_proc, ""
+// if it cannot be read
+func procName(pid int) string {
+	// {CTL_KERN, KERN_PROC, KERN_PROC_PID, pid}
+	b, err := sysctlRaw([]int32{1, 14, 1, int32(pid)})
+	if err != nil || len(b) != kinfoProcSize {
+		return ""
+	}
+	comm := b[kinfoProcCommOffset : kinfoProcCommOffset+kinfoProcCommLen]
+	if i := bytes.IndexByte(comm, 0); i >= 0 {
+		comm = comm[:i]
+	}
+	return string(comm)
+}
+
+// pcbSocks returns the sockets of the IPv4 or, if v6, the IPv6 table of
+// proto, tcp or udp, that satisfy the accept function. Both families are
+// listed by the same sysctl and told apart by the family of the socket, so an
+// IPv6 socket connected to an IPv4-mapped address is in the IPv6 table, as on
+// Linux.
+func pcbSocks(proto string, v6 bool, accept AcceptFn) ([]SockTabEntry, error) {
+	pcbs, err := readPcbs(proto)
+	if err != nil {
+		return nil, err
+	}
+	family, transport := syscall.AF_INET, proto
+	if v6 {
+		family, transport = syscall.AF_INET6, proto+"6"
+	}
+	typ, protocol := inetProto(transport)
+
+	var (
+		tab    []SockTabEntry
+		owners []*pcb
+	)
+	for i := range pcbs {
+		p := &pcbs[i]
+		if p.family != family {
+			continue
+		}
+		e := SockTabEntry{
+			Transport:  transport,
+			Type:       typ,
+			Protocol:   protocol,
+			LocalAddr:  &SockAddr{IP: p.ip(&p.laddr, v6), Port: p.lport},
+			RemoteAddr: &SockAddr{IP: p.ip(&p.faddr, v6), Port: p.fport},
+			State:      p.state,
+			TxQueue:    p.txq,
+			RxQueue:    p.rxq,
+			UID:        p.uid,
+		}
+		// UDP sockets have no state; tell connected ones apart like Linux
+		if proto == "udp" && p.fport != 0 {
+			e.State = Established
+		}
+		if accept(&e) {
+			tab = append(tab, e)
+			owners = append(owners, p)
+		}
+	}
+
+	if len(tab) != 0 {
+		lookupPids(owners)
+		procs := make(map[int]*Process)
+		for i, p := range owners {
+			if p.pid <= 0 {
+				continue
+			}
+			proc, ok := procs[p.pid]
+			if !ok {
+				proc = &Process{Pid: p.pid, Name: procName(p.pid)}
+				procs[p.pid] = proc
+			}
+			tab[i].Process = proc
+		}
+	}
+	return tab, nil
+}
+
+// TCPSocks returns a slice of active TCP sockets containing only those
+// elements that satisfy the accept function
+func osTCPSocks(accept AcceptFn) ([]SockTabEntry, error) {
+	return pcbSocks("tcp", false, accept)
+}
+
+// TCP6Socks returns a slice of active TCP IPv6 sockets containing only those
+// elements that satisfy the accept function
+func osTCP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
+	return pcbSocks("tcp", true, accept)
+}
+
+// UDPSocks returns a slice of active UDP sockets containing only those
+// elements that satisfy the accept function
+func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
+	return pcbSocks("udp", false, accept)
+}
+
+// UDP6Socks returns a slice of active UDP IPv6 sockets containing only those
+// elements that satisfy the accept function
+func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
+	return pcbSocks("udp", true, accept)
+}
@@ -0,0 +1,123 @@
+package netstat
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Kinds of the structures listed by the pcblist_n sysctls, XSO_*
+const (
+	xsoSocket = 0x01
+	xsoRcvBuf = 0x02
+	xsoSndBuf = 0x04
+	xsoStats  = 0x08
+	xsoInPCB  = 0x10
+	xsoTCPCB  = 0x20
+)
+
+// Sizes of the parts of struct xinpcb_n, xsocket_n, xsockbuf_n and xtcpcb_n
+// read by readPcbs
+const (
+	xinpgenSize   = 24
+	xinpcbNSize   = 84
+	xsocketNSize  = 76
+	xsockbufNSize = 12
+	xtcpcbNSize   = 40
+)
+
+// Layout of struct kinfo_proc, see procName
+const (
+	kinfoProcSize       = 648
+	kinfoProcCommOffset = 243 // kp_proc.p_comm
+	kinfoProcCommLen    = 17
+)
+
+// readPcbs reads the protocol control blocks of proto from the
+// net.inet.<proto>.pcblist_n sysctl. The list starts and ends with a struct
+// xinpgen. In between, each socket is listed as a sequence of structures,
+// each starting with its length and kind and padded to 8 bytes: xinpcb_n,
+// xsocket_n, xsockbuf_n for the receive and send buffers, xsockstat_n and,
+// for TCP, xtcpcb_n. The owning process is the last one that used the
+// socket, so_last_pid.
+func readPcbs(proto string) ([]pcb, error) {
+	name := "net.inet." + proto + ".pcblist_n"
+	b, err := sysctlByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < xinpgenSize {
+		return nil, fmt.Errorf("gonetstat: %s: truncated list", name)
+	}
+	all := uint32(xsoSocket | xsoRcvBuf | xsoSndBuf | xsoStats | xsoInPCB)
+	if proto == "tcp" {
+		all |= xsoTCPCB
+	}
+
+	le := binary.LittleEndian
+	var (
+		pcbs []pcb
+		p    pcb
+		seen uint32
+	)
+	for off := roundup8(int(le.Uint32(b))); off+8 <= len(b); {
+		n, kind := int(le.Uint32(b[off:])), le.Uint32(b[off+4:])
+		if n <= xinpgenSize {
+			// The closing xinpgen
+			break
+		}
+		if off+n > len(b) {
+			return nil, fmt.Errorf("gonetstat: %s: truncated entry at offset %d", name, off)
+		}
+		x := b[off : off+n]
+		var min int
+		switch kind {
+		case xsoSocket:
+			min = xsocketNSize
+		case xsoRcvBuf, xsoSndBuf:
+			min = xsockbufNSize
+		case xsoInPCB:
+			min = xinpcbNSize
+		case xsoTCPCB:
+			min = xtcpcbNSize
+		}
+		if n < min {
+			return nil, fmt.Errorf("gonetstat: %s: short structure of kind %#x at offset %d", name, kind, off)
+		}
+		switch kind {
+		case xsoSocket:
+			p.so = le.Uint64(x[8:])
+			p.family = int(int32(le.Uint32(x[44:])))
+			p.uid = le.Uint32(x[68:])
+			p.pid = int(int32(le.Uint32(x[72:])))
+		case xsoRcvBuf:
+			p.rxq = uint64(le.Uint32(x[8:]))
+		case xsoSndBuf:
+			p.txq = uint64(le.Uint32(x[8:]))
+		case xsoInPCB:
+			p.fport = binary.BigEndian.Uint16(x[16:])
+			p.lport = binary.BigEndian.Uint16(x[18:])
+			p.vflag = x[48]
+			copy(p.faddr[:], x[52:68])
+			copy(p.laddr[:], x[68:84])
+		case xsoTCPCB:
+			p.state = SkState(le.Uint32(x[36:]))
+		}
+		seen |= kind
+		if seen&all == all {
+			pcbs = append(pcbs, p)
+			p, seen = pcb{}, 0
+		}
+		off += roundup8(n)
+	}
+	return pcbs, nil
+}
+
+// lookupPids is a no-op on macOS, where the pcblist lists the owning process
+// of each socket
+func lookupPids(pcbs []*pcb) {}
+
+// CheckProcessAccess reports whether the sockets' owning processes can be
+// determined, which is always the case on macOS
+func CheckProcessAccess() error {
+	return nil
+}
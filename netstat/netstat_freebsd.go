@@ -0,0 +1,155 @@
+package netstat
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Sizes of struct xinpgen and of the parts of struct xsocket and xfile read
+// below. The layouts are those of FreeBSD 12 and later, where the structures
+// start with their own length.
+const (
+	xinpgenSize = 64
+	xsocketSize = 204
+	xfileSize   = 64
+)
+
+// Offsets into struct xinpcb of the fields read by readPcbs. inp_inc follows
+// xi_socket; inp_vflag is the eighth byte from the end of the structure.
+const (
+	xinpcbSocket = 8
+	inconnFport  = 4
+	inconnLport  = 6
+	inconnFaddr  = 8
+	inconnLaddr  = 24
+	inconnSize   = 40
+)
+
+// dtypeSocket is the xf_type of a socket, DTYPE_SOCKET
+const dtypeSocket = 2
+
+// Layout of struct kinfo_proc, see procName
+const (
+	kinfoProcSize       = 1088
+	kinfoProcCommOffset = 447 // ki_comm
+	kinfoProcCommLen    = 20
+)
+
+// readPcbs reads the protocol control blocks of proto from the
+// net.inet.<proto>.pcblist sysctl. The list starts and ends with a struct
+// xinpgen and holds a struct xinpcb per UDP socket, or a struct xtcpcb, which
+// embeds it after its length, per TCP socket.
+func readPcbs(proto string) ([]pcb, error) {
+	name := "net.inet." + proto + ".pcblist"
+	b, err := sysctlByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < xinpgenSize {
+		return nil, fmt.Errorf("gonetstat: %s: truncated list", name)
+	}
+
+	le := binary.LittleEndian
+	var pcbs []pcb
+	for off := int(le.Uint64(b)); off+8 <= len(b); {
+		n := int(le.Uint64(b[off:]))
+		if n <= xinpgenSize {
+			// The closing xinpgen
+			break
+		}
+		if off+n > len(b) {
+			return nil, fmt.Errorf("gonetstat: %s: truncated entry at offset %d", name, off)
+		}
+		x := b[off : off+n]
+		inp := x
+		if proto == "tcp" {
+			inp = x[8:]
+		}
+		p, xiLen, ok := parseXinpcb(inp)
+		// t_state follows xt_inp, xt_stack[32], xt_logid[64] and 64 bytes
+		// of xt_cc and spares
+		stateOff := 8 + xiLen + 160
+		if !ok || proto == "tcp" && n < stateOff+4 {
+			return nil, fmt.Errorf("gonetstat: %s: malformed entry at offset %d", name, off)
+		}
+		if proto == "tcp" {
+			p.state = SkState(le.Uint32(x[stateOff:]))
+		}
+		pcbs = append(pcbs, p)
+		off += n
+	}
+	return pcbs, nil
+}
+
+// parseXinpcb parses the struct xinpcb at the start of b and returns it along
+// with its length
+func parseXinpcb(b []byte) (p pcb, n int, ok bool) {
+	le := binary.LittleEndian
+	if len(b) < xinpcbSocket+8 {
+		return p, 0, false
+	}
+	n = int(le.Uint64(b))
+	so := b[xinpcbSocket:]
+	soLen := int(le.Uint64(so))
+	inc := xinpcbSocket + soLen
+	if soLen < xsocketSize || n < inc+inconnSize+8 || len(b) < n {
+		return p, 0, false
+	}
+
+	p.so = le.Uint64(so[8:])
+	p.family = int(int32(le.Uint32(so[100:])))
+	p.uid = le.Uint32(so[116:])
+	p.rxq = uint64(le.Uint32(so[164:])) // so_rcv.sb_cc
+	p.txq = uint64(le.Uint32(so[200:])) // so_snd.sb_cc
+
+	p.fport = binary.BigEndian.Uint16(b[inc+inconnFport:])
+	p.lport = binary.BigEndian.Uint16(b[inc+inconnLport:])
+	copy(p.faddr[:], b[inc+inconnFaddr:])
+	copy(p.laddr[:], b[inc+inconnLaddr:])
+	p.vflag = b[n-8]
+	return p, n, true
+}
+
+// lookupPids sets the pid of pcbs from the open files listed by the
+// kern.file sysctl, matching the socket each file refers to. A socket shared
+// by several processes is attributed to the first one listed.
+func lookupPids(pcbs []*pcb) {
+	b, err := sysctlByName("kern.file")
+	if err != nil || len(b) < 8 {
+		return
+	}
+	le := binary.LittleEndian
+	size := int(le.Uint64(b))
+	if size < xfileSize {
+		return
+	}
+	socks := make(map[uint64]*pcb, len(pcbs))
+	for _, p := range pcbs {
+		socks[p.so] = p
+	}
+	for off := 0; off+size <= len(b); off += size {
+		xf := b[off:]
+		if le.Uint16(xf[32:]) != dtypeSocket {
+			continue
+		}
+		if p, ok := socks[le.Uint64(xf[56:])]; ok && p.pid == 0 {
+			p.pid = int(int32(le.Uint32(xf[8:])))
+		}
+	}
+}
+
+// CheckProcessAccess reports whether the sockets' owning processes can be
+// determined. It returns ErrLimitedProcessInfo when
+// security.bsd.see_other_uids hides other users' processes from the caller,
+// in which case Process stays nil for their sockets.
+func CheckProcessAccess() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if v, err := syscall.SysctlUint32("security.bsd.see_other_uids"); err == nil && v == 0 {
+		return ErrLimitedProcessInfo
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 // Package netstat provides primitives for getting socket information on a
 // Linux based operating system.
+//
+// Socket tables are read from /proc/net, which the kernel generates while it
+// is being read, walking its socket hash tables one bucket at a time. Every
+// line describes a single socket consistently, but a table is not an atomic
+// snapshot: sockets created or closed during the read may be missing, and a
+// socket that changes state mid-read may be listed twice. Counts taken from a
+// busy host are therefore accurate to within the churn during the read.
 package netstat
 
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -15,13 +23,16 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
 )
 
 const (
-	pathTCPTab  = "/proc/net/tcp"
-	pathTCP6Tab = "/proc/net/tcp6"
-	pathUDPTab  = "/proc/net/udp"
-	pathUDP6Tab = "/proc/net/udp6"
+	pathRawTab  = "/proc/net/raw"
+	pathRaw6Tab = "/proc/net/raw6"
+	pathUnixTab = "/proc/net/unix"
+	pathNetNs   = "/proc/self/ns/net"
+
+	pathLocalPortRange = "/proc/sys/net/ipv4/ip_local_port_range"
 
 	ipv4StrLen = 8
 	ipv6StrLen = 32
@@ -29,17 +40,19 @@ const (
 
 // Socket states
 const (
-	Established SkState = 0x01
-	SynSent             = 0x02
-	SynRecv             = 0x03
-	FinWait1            = 0x04
-	FinWait2            = 0x05
-	TimeWait            = 0x06
-	Close               = 0x07
-	CloseWait           = 0x08
-	LastAck             = 0x09
-	Listen              = 0x0a
-	Closing             = 0x0b
+	Established   SkState = 0x01
+	SynSent               = 0x02
+	SynRecv               = 0x03
+	FinWait1              = 0x04
+	FinWait2              = 0x05
+	TimeWait              = 0x06
+	Close                 = 0x07
+	CloseWait             = 0x08
+	LastAck               = 0x09
+	Listen                = 0x0a
+	Closing               = 0x0b
+	NewSynRecv            = 0x0c
+	BoundInactive         = 0x0d
 )
 
 var skStates = [...]string{
@@ -55,13 +68,75 @@ var skStates = [...]string{
 	"LAST_ACK",
 	"LISTEN",
 	"CLOSING",
+	"NEW_SYN_RECV",
+	"BOUND_INACTIVE",
 }
 
-// Errors returned by gonetstat
+// Errors returned by gonetstat. Every error caused by malformed socket table
+// contents wraps ErrParse.
 var (
-	ErrNotEnoughFields = errors.New("gonetstat: not enough fields in the line")
+	ErrParse           = errors.New("gonetstat: malformed socket table line")
+	ErrNotEnoughFields = fmt.Errorf("%w: not enough fields", ErrParse)
+	ErrBadUID          = fmt.Errorf("%w: bad uid", ErrParse)
+
+	// ErrProcNotMounted is returned when the socket tables cannot be read
+	// because no procfs is mounted on /proc, e.g. in a minimal container.
+	// It does not wrap os.ErrNotExist, which stands for a single missing
+	// table, such as tcp6 on a host without IPv6.
+	ErrProcNotMounted = errors.New("gonetstat: procfs not mounted on /proc")
 )
 
+// procSuperMagic is the file system type of procfs, PROC_SUPER_MAGIC
+const procSuperMagic = 0x9fa0
+
+// checkProcMounted returns ErrProcNotMounted unless procfs is mounted on /proc
+func checkProcMounted() error {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs("/proc", &st); err != nil || st.Type != procSuperMagic {
+		return ErrProcNotMounted
+	}
+	return nil
+}
+
+// linuxSockType maps the SOCK_* socket type values of Linux to SockType
+func linuxSockType(t uint64) SockType {
+	switch t {
+	case syscall.SOCK_STREAM:
+		return SockStream
+	case syscall.SOCK_DGRAM:
+		return SockDgram
+	case syscall.SOCK_RAW:
+		return SockRaw
+	case syscall.SOCK_SEQPACKET:
+		return SockSeqPacket
+	}
+	return 0
+}
+
+// ParseError records a malformed socket table line. Err wraps ErrParse.
+type ParseError struct {
+	File string // table file, e.g. /proc/net/tcp; empty if not read from a file
+	Line int    // line number, counting the title line as 1
+	Raw  string // the line as read
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.File == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func newParseError(line int, raw string, err error) *ParseError {
+	if !errors.Is(err, ErrParse) {
+		err = fmt.Errorf("%w: %v", ErrParse, err)
+	}
+	return &ParseError{Line: line, Raw: raw, Err: err}
+}
+
 func parseIPv4(s string) (net.IP, error) {
 	v, err := strconv.ParseUint(s, 16, 32)
 	if err != nil {
@@ -92,7 +167,7 @@ func parseIPv6(s string) (net.IP, error) {
 func parseAddr(s string) (*SockAddr, error) {
 	fields := strings.Split(s, ":")
 	if len(fields) < 2 {
-		return nil, fmt.Errorf("netstat: not enough fields: %v", s)
+		return nil, fmt.Errorf("%w: %v", ErrNotEnoughFields, s)
 	}
 	var ip net.IP
 	var err error
@@ -102,7 +177,7 @@ func parseAddr(s string) (*SockAddr, error) {
 	case ipv6StrLen:
 		ip, err = parseIPv6(fields[0])
 	default:
-		err = fmt.Errorf("netstat: bad formatted string: %v", fields[0])
+		err = fmt.Errorf("%w: bad formatted string: %v", ErrParse, fields[0])
 	}
 	if err != nil {
 		return nil, err
@@ -114,45 +189,161 @@ func parseAddr(s string) (*SockAddr, error) {
 	return &SockAddr{IP: ip, Port: uint16(v)}, nil
 }
 
-func parseSocktab(r io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
+// tcpExtFieldCount is the number of columns of a /proc/net/tcp line that
+// includes the rto, ato, quick/pingpong, snd_cwnd and ssthresh columns, which
+// the kernel omits for TIME_WAIT and request sockets
+const tcpExtFieldCount = 17
+
+// udpFieldCount is the number of columns of a /proc/net/udp or raw line, the
+// last one being the drops counter
+const udpFieldCount = 13
+
+func parseSockTabLine(fields []string, transport string) (SockTabEntry, error) {
+	var e SockTabEntry
+	if len(fields) < 12 {
+		return e, fmt.Errorf("%w: %v, %v", ErrNotEnoughFields, len(fields), fields)
+	}
+	addr, err := parseAddr(fields[1])
+	if err != nil {
+		return e, err
+	}
+	e.LocalAddr = addr
+	addr, err = parseAddr(fields[2])
+	if err != nil {
+		return e, err
+	}
+	e.RemoteAddr = addr
+	u, err := strconv.ParseUint(fields[3], 16, 8)
+	if err != nil {
+		return e, err
+	}
+	e.State = SkState(u)
+	queues := strings.Split(fields[4], ":")
+	if len(queues) < 2 {
+		return e, fmt.Errorf("%w: %v", ErrNotEnoughFields, fields[4])
+	}
+	e.TxQueue, err = strconv.ParseUint(queues[0], 16, 64)
+	if err != nil {
+		return e, err
+	}
+	e.RxQueue, err = strconv.ParseUint(queues[1], 16, 64)
+	if err != nil {
+		return e, err
+	}
+	// tr:tm->when, the pending timer and its expiry
+	timer := strings.Split(fields[5], ":")
+	if len(timer) < 2 {
+		return e, fmt.Errorf("%w: %v", ErrNotEnoughFields, fields[5])
+	}
+	e.TimerWhen, err = strconv.ParseUint(timer[1], 16, 64)
+	if err != nil {
+		return e, err
+	}
+	u, err = strconv.ParseUint(fields[7], 10, 32)
+	if err != nil {
+		return e, fmt.Errorf("%w: %q", ErrBadUID, fields[7])
+	}
+	e.UID = uint32(u)
+	e.ino = fields[9]
+	u, err = strconv.ParseUint(fields[10], 10, 32)
+	if err != nil {
+		return e, err
+	}
+	e.Ref = uint32(u)
+	e.Pointer, err = strconv.ParseUint(fields[11], 16, 64)
+	if err != nil {
+		return e, err
+	}
+	e.Type, e.Protocol = inetProto(transport)
+	switch transport {
+	case "tcp", "tcp6":
+		if len(fields) >= tcpExtFieldCount {
+			if err := parseTCPExt(&e, fields); err != nil {
+				return e, err
+			}
+		}
+	case "raw", "raw6":
+		// The local port column holds the IP protocol of a raw socket
+		e.Type = SockRaw
+		e.Protocol = int(e.LocalAddr.Port)
+		e.LocalAddr.Port = 0
+		fallthrough
+	case "udp", "udp6":
+		if len(fields) >= udpFieldCount {
+			e.Drops, err = strconv.ParseUint(fields[12], 10, 64)
+			if err != nil {
+				return e, err
+			}
+		}
+	}
+	return e, nil
+}
+
+func parseTCPExt(e *SockTabEntry, fields []string) error {
+	var err error
+	e.RTO, err = strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return err
+	}
+	e.ATO, err = strconv.ParseUint(fields[13], 10, 64)
+	if err != nil {
+		return err
+	}
+	// A listener has no congestion state: its cwnd is the initial one and
+	// the ssthresh column holds the TCP Fast Open queue length
+	if e.State == Listen {
+		return nil
+	}
+	e.SndCwnd, err = strconv.ParseUint(fields[15], 10, 64)
+	if err != nil {
+		return err
+	}
+	// ssthresh is printed as -1 while in initial slow start
+	v, err := strconv.ParseInt(fields[16], 10, 64)
+	if err != nil {
+		return err
+	}
+	if v > 0 {
+		e.SndSsthresh = uint64(v)
+	}
+	return nil
+}
+
+func parseSocktab(r io.Reader, transport string, accept AcceptFn) ([]SockTabEntry, error) {
+	return parseSocktabLines(r, transport, accept, nil)
+}
+
+// parseSocktabLines parses a socket table. A malformed line fails the whole
+// table, unless skipped is non-nil: the line is then counted in *skipped and
+// parsing goes on with the next one.
+func parseSocktabLines(r io.Reader, transport string, accept AcceptFn, skipped *int) ([]SockTabEntry, error) {
 	br := bufio.NewScanner(r)
 	tab := make([]SockTabEntry, 0, 4)
 
 	// Discard title
 	br.Scan()
 
-	for br.Scan() {
-		var e SockTabEntry
-		line := br.Text()
+	for n := 2; br.Scan(); n++ {
+		raw := br.Text()
+		line := raw
 		// Skip comments
 		if i := strings.Index(line, "#"); i >= 0 {
 			line = line[:i]
 		}
 		fields := strings.Fields(line)
-		if len(fields) < 12 {
-			return nil, fmt.Errorf("netstat: not enough fields: %v, %v", len(fields), fields)
-		}
-		addr, err := parseAddr(fields[1])
-		if err != nil {
-			return nil, err
-		}
-		e.LocalAddr = addr
-		addr, err = parseAddr(fields[2])
-		if err != nil {
-			return nil, err
-		}
-		e.RemoteAddr = addr
-		u, err := strconv.ParseUint(fields[3], 16, 8)
-		if err != nil {
-			return nil, err
+		// Skip blank and comment-only lines
+		if len(fields) == 0 {
+			continue
 		}
-		e.State = SkState(u)
-		u, err = strconv.ParseUint(fields[7], 10, 32)
+		e, err := parseSockTabLine(fields, transport)
 		if err != nil {
-			return nil, err
+			if skipped != nil {
+				*skipped++
+				continue
+			}
+			return nil, newParseError(n, raw, err)
 		}
-		e.UID = uint32(u)
-		e.ino = fields[9]
+		e.Transport = transport
 		if accept(&e) {
 			tab = append(tab, e)
 		}
@@ -160,13 +351,6 @@ func parseSocktab(r io.Reader, accept AcceptFn) ([]SockTabEntry, error) {
 	return tab, br.Err()
 }
 
-type procFd struct {
-	base  string
-	pid   int
-	sktab []SockTabEntry
-	p     *Process
-}
-
 const sockPrefix = "socket:["
 
 func getProcName(s []byte) string {
@@ -184,77 +368,192 @@ func getProcName(s []byte) string {
 	return string(s[i+1 : j])
 }
 
-func (p *procFd) iterFdDir() {
-	// link name is of the form socket:[5860846]
-	fddir := path.Join(p.base, "/fd")
-	fi, err := ioutil.ReadDir(fddir)
+// readProcName returns the name of the process whose /proc directory is base
+func readProcName(base string) (string, error) {
+	var buf [128]byte
+	stat, err := os.Open(path.Join(base, "stat"))
 	if err != nil {
-		return
+		return "", err
 	}
-	var buf [128]byte
+	n, err := stat.Read(buf[:])
+	stat.Close()
+	if err != nil {
+		return "", err
+	}
+	z := bytes.SplitN(buf[:n], []byte(" "), 3)
+	if len(z) < 2 {
+		return "", fmt.Errorf("netstat: bad formatted string: %q", buf[:n])
+	}
+	return getProcName(z[1]), nil
+}
 
-	for _, file := range fi {
-		fd := path.Join(fddir, file.Name())
-		lname, err := os.Readlink(fd)
-		if err != nil || !strings.HasPrefix(lname, sockPrefix) {
-			continue
+// extractProcInfo sets the Process of the entries of sktab from the file
+// descriptors of all processes. A socket shared by several processes is
+// attributed to the last one found.
+func extractProcInfo(sktab []SockTabEntry) {
+	byIno := make(map[string][]int, len(sktab))
+	for i := range sktab {
+		byIno[sktab[i].ino] = append(byIno[sktab[i].ino], i)
+	}
+	procs := make(map[int]*Process)
+	walkSocketFds(context.Background(), func(pid int, base, ino string) bool {
+		idx, ok := byIno[ino]
+		if !ok {
+			return true
 		}
-
-		for i := range p.sktab {
-			sk := &p.sktab[i]
-			ss := sockPrefix + sk.ino + "]"
-			if ss != lname {
-				continue
+		p, ok := procs[pid]
+		if !ok {
+			name, err := readProcName(base)
+			if err != nil {
+				// Gone or not inspectable, keep it from being retried
+				procs[pid] = nil
+				return true
 			}
-			if p.p == nil {
-				stat, err := os.Open(path.Join(p.base, "stat"))
-				if err != nil {
-					return
-				}
-				n, err := stat.Read(buf[:])
-				stat.Close()
-				if err != nil {
-					return
-				}
-				z := bytes.SplitN(buf[:n], []byte(" "), 3)
-				name := getProcName(z[1])
-				p.p = &Process{p.pid, name}
-			}
-			sk.Process = p.p
+			p = &Process{Pid: pid, Name: name}
+			procs[pid] = p
+		}
+		if p == nil {
+			return true
+		}
+		for _, i := range idx {
+			sktab[i].Process = p
+		}
+		return true
+	})
+}
+
+// protoTabs lists the protocols this package can read, with a table under
+// /proc/net that exists if the kernel supports the protocol
+var protoTabs = []struct {
+	name, table string
+}{
+	{"tcp", "tcp"},
+	{"tcp6", "tcp6"},
+	{"udp", "udp"},
+	{"udp6", "udp6"},
+	{"raw", "raw"},
+	{"raw6", "raw6"},
+	{"unix", "unix"},
+	{"sctp", "sctp/eps"},
+}
+
+// SupportedProtocols returns the protocols whose socket tables exist under
+// procRoot, e.g. "tcp6" is missing on a kernel without IPv6 and "sctp" unless
+// the sctp module is loaded. An empty procRoot stands for /proc.
+func SupportedProtocols(procRoot string) []string {
+	if procRoot == "" {
+		procRoot = "/proc"
+	}
+	var protos []string
+	for _, p := range protoTabs {
+		if _, err := os.Stat(path.Join(procRoot, "net", p.table)); err == nil {
+			protos = append(protos, p.name)
 		}
 	}
+	return protos
 }
 
-func extractProcInfo(sktab []SockTabEntry) {
-	const basedir = "/proc"
-	fi, err := ioutil.ReadDir(basedir)
+// EphemeralPortRange returns the range of local ports, both ends included,
+// the kernel picks from for outbound connections
+func EphemeralPortRange() (first, last uint16, err error) {
+	b, err := ioutil.ReadFile(pathLocalPortRange)
 	if err != nil {
-		return
+		return 0, 0, err
 	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("netstat: bad formatted string: %q", b)
+	}
+	v, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	w, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint16(v), uint16(w), nil
+}
 
-	for _, file := range fi {
-		if !file.IsDir() {
-			continue
-		}
-		pid, err := strconv.Atoi(file.Name())
-		if err != nil {
-			continue
+// EphemeralPortUtilization returns the percentage of the ephemeral port range
+// in use by the connections in entries. A port counts once, however many
+// connections share it with different remote endpoints.
+func EphemeralPortUtilization(entries []SockTabEntry) (float64, error) {
+	first, last, err := EphemeralPortRange()
+	if err != nil {
+		return 0, err
+	}
+	if last < first {
+		return 0, fmt.Errorf("netstat: empty local port range %d-%d", first, last)
+	}
+	var used int
+	for port := range LocalPortUsage(entries) {
+		if port >= first && port <= last {
+			used++
 		}
-		base := path.Join(basedir, file.Name())
-		proc := procFd{base: base, pid: pid, sktab: sktab}
-		proc.iterFdDir()
 	}
+	return float64(used) * 100 / float64(int(last)-int(first)+1), nil
+}
+
+// CheckProcessAccess reports whether the sockets' owning processes can be
+// determined. It returns ErrLimitedProcessInfo when the caller may not inspect
+// other users' file descriptors, in which case Process stays nil for most
+// entries. This is a warning: the socket tables themselves are still readable.
+func CheckProcessAccess() error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	// Without root, capabilities such as CAP_SYS_PTRACE may still grant
+	// access, so probe the fd directory of init
+	if _, err := ioutil.ReadDir("/proc/1/fd"); err != nil {
+		return ErrLimitedProcessInfo
+	}
+	return nil
+}
+
+// HostNetNsInode returns the inode number of the network namespace the
+// calling process belongs to, which is the namespace whose sockets are listed
+// in /proc/net
+func HostNetNsInode() (uint64, error) {
+	fi, err := os.Stat(pathNetNs)
+	if err != nil {
+		return 0, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("netstat: unexpected stat type for %v", pathNetNs)
+	}
+	return st.Ino, nil
 }
 
+// tabParser parses the socket table read from r
+type tabParser func(r io.Reader, transport string, accept AcceptFn) ([]SockTabEntry, error)
+
 // doNetstat - collect information about network port status
-func doNetstat(path string, fn AcceptFn) ([]SockTabEntry, error) {
-	f, err := os.Open(path)
+func doNetstat(file string, parse tabParser, fn AcceptFn) ([]SockTabEntry, error) {
+	f, err := os.Open(file)
 	if err != nil {
+		// Tell a missing table apart from a missing /proc
+		if errors.Is(err, os.ErrNotExist) {
+			if perr := checkProcMounted(); perr != nil {
+				return nil, perr
+			}
+		}
 		return nil, err
 	}
-	tabs, err := parseSocktab(f, fn)
+	// /proc/net lists the sockets of our own network namespace
+	netns, _ := HostNetNsInode()
+	// The table's file name doubles as the transport name
+	tabs, err := parse(f, path.Base(file), func(e *SockTabEntry) bool {
+		e.NetNSInode = netns
+		return fn(e)
+	})
 	f.Close()
 	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			pe.File = file
+		}
 		return nil, err
 	}
 
@@ -265,26 +564,134 @@ func doNetstat(path string, fn AcceptFn) ([]SockTabEntry, error) {
 	return tabs, nil
 }
 
+// LookupByInodes returns the sockets with the given inode numbers, keyed by
+// inode. The TCP and UDP tables are read in turn until every inode has been
+// found; inodes that belong to no socket are absent from the result. Tables
+// missing on this host (e.g. IPv6 disabled) are skipped.
+func LookupByInodes(inodes []uint64) (map[uint64]SockTabEntry, error) {
+	want := make(map[string]uint64, len(inodes))
+	for _, ino := range inodes {
+		want[strconv.FormatUint(ino, 10)] = ino
+	}
+	found := make(map[uint64]SockTabEntry, len(inodes))
+	for _, ts := range transportSocks {
+		if len(found) == len(want) {
+			break
+		}
+		tabs, err := doNetstat(procNetPath(ts.name), parseSocktab, func(s *SockTabEntry) bool {
+			_, ok := want[s.ino]
+			return ok
+		})
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range tabs {
+			found[want[e.ino]] = e
+		}
+	}
+	return found, nil
+}
+
+// procNetDir is the directory of the tables of transportSocks, changed by
+// tests to read sample tables
+var procNetDir = "/proc/net"
+
+// procNetPath returns the path of the /proc/net table name, such as the
+// tables of transportSocks
+func procNetPath(name string) string {
+	return procNetDir + "/" + name
+}
+
+// LenientSocks is like Socks, but skips malformed table lines instead of
+// failing. A line may be cut short while the kernel tears down the socket it
+// describes, which would otherwise lose the whole table on a busy host. The
+// number of skipped lines is returned along with the entries. Tables that
+// cannot be read are reported in a MultiError, as by Socks.
+func LenientSocks(t Transports, accept AcceptFn) ([]SockTabEntry, int, error) {
+	var skipped int
+	parse := func(r io.Reader, transport string, accept AcceptFn) ([]SockTabEntry, error) {
+		return parseSocktabLines(r, transport, accept, &skipped)
+	}
+	var (
+		tabs []SockTabEntry
+		errs MultiError
+	)
+	for _, ts := range transportSocks {
+		if t&ts.t == 0 {
+			continue
+		}
+		tab, err := doNetstat(procNetPath(ts.name), parse, accept)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		tabs = append(tabs, tab...)
+	}
+	if errs != nil {
+		return tabs, skipped, errs
+	}
+	return tabs, skipped, nil
+}
+
+// ParseProcNet parses a socket table in the format of /proc/net/<transport>
+// read from r, e.g. a snapshot captured on another host, and returns the
+// entries satisfying the accept function. transport is one of tcp, tcp6,
+// udp, udp6, raw, raw6, unix, sctp/eps and sctp/assocs. Owning processes are
+// not looked up, as the snapshot need not come from this host; NetNSInode
+// stays zero for the same reason.
+// The kernel prints addresses in its native byte order; like the rest of the
+// package, the parser expects a little-endian host.
+func ParseProcNet(r io.Reader, transport string, accept AcceptFn) ([]SockTabEntry, error) {
+	switch transport {
+	case "tcp", "tcp6", "udp", "udp6", "raw", "raw6":
+		return parseSocktab(r, transport, accept)
+	case "unix":
+		return parseUnixSockTab(r, transport, accept)
+	case "sctp/eps":
+		return sctpTabParser(parseSCTPEpsLine)(r, transport, accept)
+	case "sctp/assocs":
+		return sctpTabParser(parseSCTPAssocsLine)(r, transport, accept)
+	}
+	return nil, fmt.Errorf("gonetstat: unsupported transport %q", transport)
+}
+
 // TCPSocks returns a slice of active TCP sockets containing only those
 // elements that satisfy the accept function
 func osTCPSocks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathTCPTab, accept)
+	return doNetstat(procNetPath("tcp"), parseSocktab, accept)
 }
 
 // TCP6Socks returns a slice of active TCP IPv4 sockets containing only those
 // elements that satisfy the accept function
 func osTCP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathTCP6Tab, accept)
+	return doNetstat(procNetPath("tcp6"), parseSocktab, accept)
 }
 
 // UDPSocks returns a slice of active UDP sockets containing only those
 // elements that satisfy the accept function
 func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathUDPTab, accept)
+	return doNetstat(procNetPath("udp"), parseSocktab, accept)
 }
 
 // UDP6Socks returns a slice of active UDP IPv6 sockets containing only those
 // elements that satisfy the accept function
 func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
-	return doNetstat(pathUDP6Tab, accept)
+	return doNetstat(procNetPath("udp6"), parseSocktab, accept)
+}
+
+// RawSocks returns a slice of raw IPv4 sockets containing only those
+// elements that satisfy the accept function. Raw sockets have no ports: the
+// IP protocol they were opened for (e.g. 1 for ICMP) is in Protocol and both
+// ports are zero.
+func RawSocks(accept AcceptFn) ([]SockTabEntry, error) {
+	return doNetstat(pathRawTab, parseSocktab, accept)
+}
+
+// Raw6Socks returns a slice of raw IPv6 sockets containing only those
+// elements that satisfy the accept function, see RawSocks
+func Raw6Socks(accept AcceptFn) ([]SockTabEntry, error) {
+	return doNetstat(pathRaw6Tab, parseSocktab, accept)
 }
@@ -0,0 +1,309 @@
+package netstat
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const tcpHeader = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+
+func parseTCPLines(t *testing.T, lines ...string) []SockTabEntry {
+	t.Helper()
+	tab, err := parseSocktab(strings.NewReader(tcpHeader+strings.Join(lines, "\n")), "tcp", NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tab) != len(lines) {
+		t.Fatalf("got %d entries, want %d", len(tab), len(lines))
+	}
+	return tab
+}
+
+func TestParseTCPExt(t *testing.T) {
+	tab := parseTCPLines(t,
+		// Established, past slow start
+		"   2: 0100007F:CB2A 0100007F:BC8F 01 00000000:00000000 02:00000104 00000000     0        0 48263 3 00000000154e4b50 20 4 0 14 10",
+		// Established, in initial slow start
+		"   3: 0100007F:BC8F 0100007F:CB2A 01 00000000:00000000 00:00000000 00000000 65534        0 48264 2 00000000ccb04af6 20 4 2 27 -1",
+		// Listener with a TCP Fast Open queue of 256
+		"   0: 00000000:07E8 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 662 1 000000002e784811 100 0 0 10 256",
+		// Without the trailing columns
+		"   4: 0100007F:0016 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 7001 1 0000000000000000",
+	)
+	want := []struct {
+		rto, ato, cwnd, ssthresh uint64
+	}{
+		{20, 4, 14, 10},
+		{20, 4, 27, 0},
+		{100, 0, 0, 0},
+		{0, 0, 0, 0},
+	}
+	for i, w := range want {
+		e := tab[i]
+		if e.RTO != w.rto || e.ATO != w.ato || e.SndCwnd != w.cwnd || e.SndSsthresh != w.ssthresh {
+			t.Errorf("line %d: got RTO %d ATO %d SndCwnd %d SndSsthresh %d, want %d %d %d %d",
+				i, e.RTO, e.ATO, e.SndCwnd, e.SndSsthresh, w.rto, w.ato, w.cwnd, w.ssthresh)
+		}
+	}
+}
+
+func TestParseSockTabKernelSamples(t *testing.T) {
+	tests := []struct {
+		kernel    string
+		transport string
+		line      string
+		ip        string
+		port      uint16
+		uid       uint32
+		ino       string
+		ref       uint32
+		pointer   uint64
+		drops     uint64
+		is4in6    bool
+	}{
+		{
+			kernel:    "5.4, pointer hidden by kptr_restrict",
+			transport: "tcp",
+			line:      "   0: 3500007F:0035 00000000:0000 0A 00000000:00000000 00:00000000 00000000   101        0 30245 1 0000000000000000 100 0 0 10 0",
+			ip:        "127.0.0.53", port: 53, uid: 101, ino: "30245", ref: 1,
+		},
+		{
+			kernel:    "5.15",
+			transport: "tcp6",
+			line:      "   1: 00000000000000000000000001000000:0277 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 35614 1 ffff9a0c4b5e8000 100 0 0 10 0",
+			ip:        "::1", port: 631, ino: "35614", ref: 1, pointer: 0xffff9a0c4b5e8000,
+		},
+		{
+			kernel:    "5.15, IPv4-mapped connection",
+			transport: "tcp6",
+			line:      "   2: 0000000000000000FFFF00000100007F:1F90 0000000000000000FFFF00000100007F:C350 01 00000000:00000000 00:00000000 00000000  1000        0 41234 1 ffff9a0c4b5e8800 20 4 30 10 -1",
+			ip:        "127.0.0.1", port: 8080, uid: 1000, ino: "41234", ref: 1, pointer: 0xffff9a0c4b5e8800, is4in6: true,
+		},
+		{
+			kernel:    "6.x, hashed pointer",
+			transport: "udp",
+			line:      "  455: 00000000:0044 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 21734 2 00000000f1e2d3c4 12",
+			ip:        "0.0.0.0", port: 68, ino: "21734", ref: 2, pointer: 0xf1e2d3c4, drops: 12,
+		},
+		{
+			kernel:    "future kernel with an extra trailing column",
+			transport: "udp6",
+			line:      "  455: 00000000000000000000000000000000:14E9 00000000000000000000000000000000:0000 07 00000000:00000000 00:00000000 00000000   108        0 19880 2 000000004d3c2b1a 3 77",
+			ip:        "::", port: 5353, uid: 108, ino: "19880", ref: 2, pointer: 0x4d3c2b1a, drops: 3,
+		},
+	}
+	for _, tt := range tests {
+		tab, err := parseSocktab(strings.NewReader(tcpHeader+tt.line), tt.transport, NoopFilter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.kernel, err)
+			continue
+		}
+		if len(tab) != 1 {
+			t.Errorf("%s: got %d entries, want 1", tt.kernel, len(tab))
+			continue
+		}
+		e := tab[0]
+		if e.LocalAddr.IP.String() != tt.ip || e.LocalAddr.Port != tt.port {
+			t.Errorf("%s: local address %v, want %s port %d", tt.kernel, e.LocalAddr, tt.ip, tt.port)
+		}
+		if e.LocalAddr.Is4in6() != tt.is4in6 || e.RemoteAddr.Is4in6() != tt.is4in6 {
+			t.Errorf("%s: got Is4in6 %v for the local and %v for the remote address, want %v",
+				tt.kernel, e.LocalAddr.Is4in6(), e.RemoteAddr.Is4in6(), tt.is4in6)
+		}
+		if e.UID != tt.uid || e.ino != tt.ino || e.Ref != tt.ref || e.Pointer != tt.pointer || e.Drops != tt.drops {
+			t.Errorf("%s: got uid %d inode %s ref %d pointer %#x drops %d, want %d %s %d %#x %d",
+				tt.kernel, e.UID, e.ino, e.Ref, e.Pointer, e.Drops,
+				tt.uid, tt.ino, tt.ref, tt.pointer, tt.drops)
+		}
+	}
+}
+
+func TestParseStateZero(t *testing.T) {
+	tab := parseTCPLines(t,
+		"   0: 0100007F:0016 00000000:0000 00 00000000:00000000 00:00000000 00000000     0        0 7002 1 0000000000000000")
+	if s := tab[0].State; s != 0 || s.String() != "UNKNOWN" {
+		t.Errorf("got state %d %q, want 0 \"UNKNOWN\"", s, s)
+	}
+	// States past the end of the table must not index out of range either
+	if s := SkState(0xff).String(); s != "UNKNOWN(255)" {
+		t.Errorf("got %q for state 0xff, want \"UNKNOWN(255)\"", s)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  error
+		token string // quoted in the message, if set
+	}{
+		{
+			name:  "Wrong user",
+			line:  "   0: 0100007F:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000    -1        0 7003 1 0000000000000000",
+			want:  ErrBadUID,
+			token: `"-1"`,
+		},
+		{
+			name:  "Non-numeric user",
+			line:  "   0: 0100007F:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000  root        0 7003 1 0000000000000000",
+			want:  ErrBadUID,
+			token: `"root"`,
+		},
+		{
+			name: "Truncated line",
+			line: "   0: 0100007F:0016 00000000:0000 0A 00000000:00000000",
+			want: ErrNotEnoughFields,
+		},
+		{
+			name: "Bad address",
+			line: "   0: 0100007F:XYZ 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 7003 1 0000000000000000",
+			want: ErrParse,
+		},
+	}
+	for _, tt := range tests {
+		_, err := parseSocktab(strings.NewReader(tcpHeader+tt.line), "tcp", NoopFilter)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+		if !errors.Is(err, ErrParse) {
+			t.Errorf("%s: %v does not wrap ErrParse", tt.name, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), tt.token) {
+			t.Errorf("%s: %q does not quote %s", tt.name, err, tt.token)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%s: %v is not a *ParseError", tt.name, err)
+			continue
+		}
+		if pe.Line != 2 || pe.Raw != tt.line {
+			t.Errorf("%s: got line %d %q, want 2 %q", tt.name, pe.Line, pe.Raw, tt.line)
+		}
+	}
+}
+
+// withProcNet points procNetDir at a temporary directory for the duration of
+// the test and returns the directory
+func withProcNet(t *testing.T) (dir string, cleanup func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "netstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := procNetDir
+	procNetDir = dir
+	return dir, func() {
+		procNetDir = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSocksUnreadableTable(t *testing.T) {
+	dir, cleanup := withProcNet(t)
+	defer cleanup()
+	line := "   0: 0100007F:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 7004 1 0000000000000000\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "tcp"), []byte(tcpHeader+line), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A directory opens fine but cannot be read
+	if err := os.Mkdir(filepath.Join(dir, "tcp6"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// udp and udp6 are missing
+
+	tab, err := Socks(TransportAll, NoopFilter)
+	if len(tab) != 1 || tab[0].LocalAddr.Port != 22 {
+		t.Errorf("got %v, want the socket of the readable table", tab)
+	}
+	var errs MultiError
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want a MultiError", err)
+	}
+	if len(errs) != 3 {
+		t.Fatalf("got %d errors, want 3: %v", len(errs), errs)
+	}
+	if errors.Is(errs[0], os.ErrNotExist) || !strings.Contains(errs[0].Error(), "tcp6") {
+		t.Errorf("got %v first, want the read error of tcp6", errs[0])
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%v does not match os.ErrNotExist for the missing tables", err)
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	const raw = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode ref pointer drops\n" +
+		"   1: 00000000:0001 00000000:0000 07 00000000:00000000 00:00000000 00000000     0        0 30104 2 0000000000000000 5\n"
+	tab, err := ParseProcNet(strings.NewReader(raw), "raw", NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tab) != 1 {
+		t.Fatalf("got %d entries, want 1", len(tab))
+	}
+	e := tab[0]
+	if e.Type != SockRaw || e.Protocol != 1 || e.LocalAddr.Port != 0 {
+		t.Errorf("got type %v protocol %d local port %d, want RAW 1 0", e.Type, e.Protocol, e.LocalAddr.Port)
+	}
+	if e.Transport != "raw" || e.Drops != 5 {
+		t.Errorf("got transport %s drops %d, want raw 5", e.Transport, e.Drops)
+	}
+}
+
+func TestTimerExpiry(t *testing.T) {
+	tab := parseTCPLines(t,
+		"   2: 0100007F:CB2A 0100007F:BC8F 01 00000000:00000000 02:00000104 00000000     0        0 48263 3 00000000154e4b50 20 4 0 14 10")
+	e := tab[0]
+	if e.TimerWhen != 0x104 {
+		t.Fatalf("got TimerWhen %d, want %d", e.TimerWhen, 0x104)
+	}
+	// 260 ticks at USER_HZ=100
+	if d := e.TimerExpiry(); d != 2600*time.Millisecond {
+		t.Errorf("got TimerExpiry %v, want 2.6s", d)
+	}
+}
+
+func TestParseLinkLocalHasNoZone(t *testing.T) {
+	// The /proc tables carry no scope
+	tab, err := ParseProcNet(strings.NewReader(tcpHeader+
+		"   0: 000080FE000000000000000001000000:0016 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 7005 1 0000000000000000"),
+		"tcp6", NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := tab[0].LocalAddr; !a.IP.Equal(net.ParseIP("fe80::1")) || a.Zone != "" || a.String() != "fe80::1:22" {
+		t.Errorf("got %v with zone %q, want fe80::1:22 without zone", a, a.Zone)
+	}
+}
+
+func TestParseSkipsCommentsAndBlankLines(t *testing.T) {
+	const valid = tcpHeader +
+		"# test\n" +
+		"\n" +
+		"   0: 0100007F:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 7006 1 0000000000000000\n" +
+		"   \n" +
+		"   1: 0100007F:0017 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 7007 1 0000000000000000 # telnet\n"
+	tab, err := parseSocktab(strings.NewReader(valid), "tcp", NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tab) != 2 || tab[0].ino != "7006" || tab[1].ino != "7007" {
+		t.Errorf("got %v, want the sockets 7006 and 7007", tab)
+	}
+
+	// A malformed row still fails the table, at its own line number
+	bad := "   2: 0100007F:0018 00000000:0000 0A # cut short"
+	_, err = parseSocktab(strings.NewReader(valid+bad+"\n"), "tcp", NoopFilter)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got %v, want a *ParseError", err)
+	}
+	if pe.Line != 7 || pe.Raw != bad || !errors.Is(err, ErrNotEnoughFields) {
+		t.Errorf("got line %d %q: %v, want line 7 %q with not enough fields", pe.Line, pe.Raw, pe.Err, bad)
+	}
+}
@@ -0,0 +1,75 @@
+package netstat
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSSCommand(t *testing.T) {
+	tests := []struct {
+		t    Transports
+		want string
+	}{
+		{TransportTCP, "ss -tan -4"},
+		{TransportUDP6, "ss -uan -6"},
+		{TransportTCP | TransportTCP6, "ss -tan"},
+		{TransportAll, "ss -tuan"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.t.SSCommand(); got != tt.want {
+			t.Errorf("%v: got %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestWildcardAndLoopback(t *testing.T) {
+	tests := []struct {
+		ip       string
+		state    SkState
+		wildcard bool
+		loopback bool
+	}{
+		{"0.0.0.0", Listen, true, false},
+		{"::", Listen, true, false},
+		{"::ffff:0.0.0.0", Listen, true, false},
+		{"0.0.0.0", Established, false, false},
+		{"127.0.0.1", Listen, false, true},
+		{"127.1.2.3", Established, false, true},
+		{"::1", Listen, false, true},
+		{"::ffff:127.0.0.1", Listen, false, true},
+		{"192.0.2.1", Listen, false, false},
+		{"2001:db8::1", Listen, false, false},
+	}
+	for _, tt := range tests {
+		e := SockTabEntry{LocalAddr: &SockAddr{IP: net.ParseIP(tt.ip), Port: 80}, State: tt.state}
+		if got := e.IsWildcardListen(); got != tt.wildcard {
+			t.Errorf("%s %v: got IsWildcardListen %v, want %v", tt.ip, tt.state, got, tt.wildcard)
+		}
+		if got := e.IsLoopback(); got != tt.loopback {
+			t.Errorf("%s %v: got IsLoopback %v, want %v", tt.ip, tt.state, got, tt.loopback)
+		}
+	}
+	var e SockTabEntry
+	if e.IsWildcardListen() || e.IsLoopback() {
+		t.Error("got true for an entry without local address")
+	}
+}
+
+func TestSockAddrStringZone(t *testing.T) {
+	tests := []struct {
+		addr SockAddr
+		want string
+	}{
+		{SockAddr{IP: net.ParseIP("fe80::1"), Port: 22, Zone: "eth0"}, "fe80::1%eth0:22"},
+		{SockAddr{IP: net.ParseIP("fe80::1"), Port: 22, Zone: "3"}, "fe80::1%3:22"},
+		{SockAddr{IP: net.ParseIP("fe80::1"), Port: 22}, "fe80::1:22"},
+		{SockAddr{IP: net.ParseIP("2001:db8::1"), Port: 443}, "2001:db8::1:443"},
+		{SockAddr{IP: net.ParseIP("10.0.0.1").To4(), Port: 80}, "10.0.0.1:80"},
+	}
+	for _, tt := range tests {
+		if got := tt.addr.String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
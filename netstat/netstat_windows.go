@@ -189,7 +189,7 @@ type MibUDPRowOwnerPID struct {
 }
 
 func (m *MibUDPRowOwnerPID) LocalSock() *SockAddr  { return m.Sock() }
-func (m *MibUDPRowOwnerPID) RemoteSock() *SockAddr { return &SockAddr{net.IPv4zero, 0} }
+func (m *MibUDPRowOwnerPID) RemoteSock() *SockAddr { return &SockAddr{IP: net.IPv4zero} }
 func (m *MibUDPRowOwnerPID) SockState() SkState    { return Close }
 func (m *MibUDPRowOwnerPID) UID() uint32           { return uint32(m.WinPid) }
 
@@ -219,7 +219,7 @@ type MibUDP6RowOwnerPID struct {
 }
 
 func (m *MibUDP6RowOwnerPID) LocalSock() *SockAddr  { return m.Sock() }
-func (m *MibUDP6RowOwnerPID) RemoteSock() *SockAddr { return &SockAddr{net.IPv4zero, 0} }
+func (m *MibUDP6RowOwnerPID) RemoteSock() *SockAddr { return &SockAddr{IP: net.IPv4zero} }
 func (m *MibUDP6RowOwnerPID) SockState() SkState    { return Close }
 func (m *MibUDP6RowOwnerPID) UID() uint32           { return uint32(m.WinPid) }
 
@@ -476,8 +476,12 @@ type winSockEnt interface {
 	UID() uint32
 }
 
-func toSockTabEntry(ws winSockEnt) SockTabEntry {
+func toSockTabEntry(ws winSockEnt, transport string) SockTabEntry {
+	typ, proto := inetProto(transport)
 	return SockTabEntry{
+		Transport:  transport,
+		Type:       typ,
+		Protocol:   proto,
 		LocalAddr:  ws.LocalSock(),
 		RemoteAddr: ws.RemoteSock(),
 		State:      ws.SockState(),
@@ -494,7 +498,7 @@ func osTCPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i])
+		ent := toSockTabEntry(&s[i], "tcp")
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -520,19 +524,29 @@ func osTCP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	if err != nil {
 		return nil, err
 	}
-	snp, err := CreateToolhelp32Snapshot(Th32csSnapProcess, 0)
-	if err != nil {
-		return nil, err
-	}
+
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i])
+		ent := toSockTabEntry(&s[i], "tcp6")
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
 	}
-	snp.Close()
+
+	if len(sktab) != 0 {
+		snp, err := CreateToolhelp32Snapshot(Th32csSnapProcess, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range sktab {
+			sktab[i].Process = sockProcess(snp, sktab[i].UID)
+		}
+
+		snp.Close()
+	}
+
 	return sktab, nil
 }
 
@@ -545,7 +559,7 @@ func osUDPSocks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i])
+		ent := toSockTabEntry(&s[i], "udp")
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -576,7 +590,7 @@ func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 	var sktab []SockTabEntry
 	s := tbl.Rows()
 	for i := range s {
-		ent := toSockTabEntry(&s[i])
+		ent := toSockTabEntry(&s[i], "udp6")
 		if accept(&ent) {
 			sktab = append(sktab, ent)
 		}
@@ -597,3 +611,9 @@ func osUDP6Socks(accept AcceptFn) ([]SockTabEntry, error) {
 
 	return sktab, nil
 }
+
+// CheckProcessAccess reports whether the sockets' owning processes can be
+// determined, which is always the case on Windows
+func CheckProcessAccess() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build amd64 || arm64
+// +build amd64 arm64
+
+package netstat
+
+import (
+	"net"
+	"os"
+	"testing"
+)
+
+func TestTCP6SocksSmoke(t *testing.T) {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 unavailable: %v", err)
+	}
+	defer l.Close()
+	c, err := net.Dial("tcp6", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	port := uint16(l.Addr().(*net.TCPAddr).Port)
+	tab, err := TCP6Socks(Or(FilterByLocalPort(port), FilterByRemotePort(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The listener and both ends of the connection
+	if len(tab) != 3 {
+		t.Fatalf("got %d sockets, want 3: %v", len(tab), tab)
+	}
+	var listeners int
+	for _, e := range tab {
+		if e.State == Listen {
+			listeners++
+		}
+		if e.Transport != "tcp6" || e.Type != SockStream || e.Protocol != protoTCP {
+			t.Errorf("%v: got transport %s type %v protocol %d, want tcp6 STREAM %d",
+				e.LocalAddr, e.Transport, e.Type, e.Protocol, protoTCP)
+		}
+		if e.Process == nil || e.Process.Pid != os.Getpid() {
+			t.Errorf("%v: got process %v, want pid %d", e.LocalAddr, e.Process, os.Getpid())
+		}
+	}
+	if listeners != 1 {
+		t.Errorf("got %d listeners, want 1", listeners)
+	}
+}
@@ -0,0 +1,385 @@
+package netstat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+func (p *Process) procFile(name string) string {
+	return path.Join(procDir, strconv.Itoa(p.Pid), name)
+}
+
+// LoadEnv reads the process environment from /proc/<pid>/environ and stores
+// the variables named by keys in p.Env. Other variables are never kept, as
+// the environment may hold secrets, so the caller must list every key it is
+// interested in. Reading another user's environment requires root.
+func (p *Process) LoadEnv(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	b, err := ioutil.ReadFile(p.procFile("environ"))
+	if err != nil {
+		return err
+	}
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	env := make(map[string]string)
+	for _, kv := range bytes.Split(b, []byte{0}) {
+		i := bytes.IndexByte(kv, '=')
+		if i < 0 {
+			continue
+		}
+		if k := string(kv[:i]); want[k] {
+			env[k] = string(kv[i+1:])
+		}
+	}
+	p.Env = env
+	return nil
+}
+
+// LoadCmdline reads the process command line from /proc/<pid>/cmdline into
+// p.Cmdline. It is not read by default as it costs an extra file read per
+// process. Kernel threads and zombies have an empty command line, which
+// leaves p.Cmdline nil.
+func (p *Process) LoadCmdline() error {
+	b, err := ioutil.ReadFile(p.procFile("cmdline"))
+	if err != nil {
+		return err
+	}
+	b = bytes.TrimSuffix(b, []byte{0})
+	if len(b) == 0 {
+		p.Cmdline = nil
+		return nil
+	}
+	args := bytes.Split(b, []byte{0})
+	p.Cmdline = make([]string, len(args))
+	for i, a := range args {
+		p.Cmdline[i] = string(a)
+	}
+	return nil
+}
+
+// LoadExe reads the path of the process executable from the /proc/<pid>/exe
+// symlink into p.Exe. Reading the link of a process owned by another user
+// requires root; on failure p.Exe is left empty and the error returned, which
+// callers scanning many processes may ignore.
+func (p *Process) LoadExe() error {
+	exe, err := os.Readlink(p.procFile("exe"))
+	if err != nil {
+		return err
+	}
+	p.Exe = exe
+	return nil
+}
+
+// containerIDLen is the length of a docker/containerd container id in hex
+const containerIDLen = 64
+
+// LoadContainerID reads /proc/<pid>/cgroup and stores the id of the container
+// the process runs in in p.ContainerID. Both the cgroup v1 and v2 layouts are
+// understood, with the id either as a path component of its own
+// (/docker/<id>) or inside a systemd scope (docker-<id>.scope,
+// cri-containerd-<id>.scope). ContainerID is left empty for processes
+// outside a container.
+func (p *Process) LoadContainerID() error {
+	cgroups, err := p.readCgroups()
+	if err != nil {
+		return err
+	}
+	for _, cg := range cgroups {
+		if id := cgroupContainerID(cg[2]); id != "" {
+			p.ContainerID = id
+			return nil
+		}
+	}
+	return nil
+}
+
+// readCgroups returns the lines of /proc/<pid>/cgroup split into hierarchy
+// id, controller list and cgroup path
+func (p *Process) readCgroups() ([][]string, error) {
+	b, err := ioutil.ReadFile(p.procFile("cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	var cgroups [][]string
+	for _, line := range strings.Split(string(b), "\n") {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) == 3 {
+			cgroups = append(cgroups, fields)
+		}
+	}
+	return cgroups, nil
+}
+
+// ErrNoProcess is returned for sockets whose owning process is unknown
+var ErrNoProcess = errors.New("gonetstat: socket owner unknown")
+
+// CgroupPath returns the cgroup of the process owning the socket, read from
+// /proc/<pid>/cgroup. On hosts using the unified hierarchy (cgroup v2, or a
+// hybrid setup) this is the v2 path, otherwise the path in the first listed
+// v1 hierarchy. ErrNoProcess is returned if the owner is unknown.
+func (e *SockTabEntry) CgroupPath() (string, error) {
+	if e.Process == nil {
+		return "", ErrNoProcess
+	}
+	cgroups, err := e.Process.readCgroups()
+	if err != nil {
+		return "", err
+	}
+	for _, cg := range cgroups {
+		// the unified hierarchy has id 0 and no controllers
+		if cg[0] == "0" && cg[1] == "" {
+			return cg[2], nil
+		}
+	}
+	if len(cgroups) == 0 {
+		return "", fmt.Errorf("gonetstat: no cgroup listed for pid %d", e.Process.Pid)
+	}
+	return cgroups[0][2], nil
+}
+
+// cgroupContainerID returns the container id found in a cgroup path, the
+// innermost one if there are several
+func cgroupContainerID(cgpath string) string {
+	parts := strings.Split(cgpath, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		id := strings.TrimSuffix(parts[i], ".scope")
+		if j := strings.LastIndexByte(id, '-'); j >= 0 {
+			id = id[j+1:]
+		}
+		if isContainerID(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+func isContainerID(s string) bool {
+	if len(s) != containerIDLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// readDirNames returns the names of the entries of dir without stat'ing them
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	return names, err
+}
+
+// procDir is the directory walkSocketFds lists processes from and the
+// Process loaders read from, changed by tests to use a mocked /proc
+var procDir = "/proc"
+
+// walkSocketFds calls fn with the pid, /proc directory and socket inode of
+// every socket file descriptor of every process. Processes that cannot be
+// inspected are skipped. The walk stops when fn returns false, or when ctx is
+// done, in which case ctx.Err() is returned. ctx is checked before every file
+// descriptor, so a process with many of them does not delay cancellation.
+func walkSocketFds(ctx context.Context, fn func(pid int, base, ino string) bool) error {
+	basedir := procDir
+	pids, err := readDirNames(basedir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range pids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		base := path.Join(basedir, name)
+		fddir := path.Join(base, "fd")
+		fds, err := readDirNames(fddir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			// link name is of the form socket:[5860846]
+			lname, err := os.Readlink(path.Join(fddir, fd))
+			if err != nil || !strings.HasPrefix(lname, sockPrefix) {
+				continue
+			}
+			ino := strings.TrimSuffix(lname[len(sockPrefix):], "]")
+			if !fn(pid, base, ino) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// procFdRecord is a line of the stream written by WriteProcessFDs
+type procFdRecord struct {
+	Inode uint64 `json:"inode"`
+	Pid   int    `json:"pid"`
+	Name  string `json:"name"`
+}
+
+// WriteProcessFDs walks the file descriptors of all processes and writes
+// the first process found owning each socket inode to w, one JSON object per
+// line. It is meant to run in a small privileged helper, while the
+// unprivileged side reads the stream with ProcessResolverFromReader. If ctx
+// is done first, ctx.Err() is returned and the records already written form
+// a valid but partial stream.
+func WriteProcessFDs(ctx context.Context, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	names := make(map[int]string)
+	seen := make(map[uint64]bool)
+	var werr error
+	err := walkSocketFds(ctx, func(pid int, base, ino string) bool {
+		inode, err := strconv.ParseUint(ino, 10, 64)
+		if err != nil || seen[inode] {
+			return true
+		}
+		seen[inode] = true
+		name, ok := names[pid]
+		if !ok {
+			name, _ = readProcName(base)
+			names[pid] = name
+		}
+		werr = enc.Encode(procFdRecord{Inode: inode, Pid: pid, Name: name})
+		return werr == nil
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// ProcessResolver maps socket inodes to their owning processes
+type ProcessResolver map[uint64]*Process
+
+// ProcessResolverFromReader builds a ProcessResolver from the stream written
+// by WriteProcessFDs. Should an inode be listed more than once, the first
+// process listed wins.
+func ProcessResolverFromReader(r io.Reader) (ProcessResolver, error) {
+	res := make(ProcessResolver)
+	procs := make(map[int]*Process)
+	dec := json.NewDecoder(r)
+	for {
+		var rec procFdRecord
+		if err := dec.Decode(&rec); err != nil {
+			if err == io.EOF {
+				return res, nil
+			}
+			return nil, err
+		}
+		if _, ok := res[rec.Inode]; ok {
+			continue
+		}
+		p, ok := procs[rec.Pid]
+		if !ok {
+			p = &Process{Pid: rec.Pid, Name: rec.Name}
+			procs[rec.Pid] = p
+		}
+		res[rec.Inode] = p
+	}
+}
+
+// ResolveInodes finds the processes owning the given socket inodes by walking
+// the file descriptors of all processes. The walk ends as soon as every inode
+// has been found, so resolving a handful of sockets is usually much cheaper
+// than a full scan. Inodes no inspectable process owns are absent from the
+// result, as are those of processes whose stat file cannot be read. If ctx
+// is done before the walk ends, the inodes resolved so far are returned
+// along with ctx.Err().
+func ResolveInodes(ctx context.Context, inodes []uint64) (ProcessResolver, error) {
+	want := make(map[string]uint64, len(inodes))
+	for _, ino := range inodes {
+		want[strconv.FormatUint(ino, 10)] = ino
+	}
+	res := make(ProcessResolver, len(want))
+	procs := make(map[int]*Process)
+	if len(want) == 0 {
+		return res, nil
+	}
+	err := walkSocketFds(ctx, func(pid int, base, ino string) bool {
+		inode, ok := want[ino]
+		if !ok {
+			return true
+		}
+		if _, ok := res[inode]; ok {
+			return true
+		}
+		p, ok := procs[pid]
+		if !ok {
+			name, err := readProcName(base)
+			if err != nil {
+				// Gone or not inspectable, like in extractProcInfo
+				procs[pid] = nil
+				return true
+			}
+			p = &Process{Pid: pid, Name: name}
+			procs[pid] = p
+		}
+		if p == nil {
+			return true
+		}
+		res[inode] = p
+		return len(res) < len(want)
+	})
+	return res, err
+}
+
+// Attach sets the Process of every entry whose socket inode is known to r
+func (r ProcessResolver) Attach(tab []SockTabEntry) {
+	for i := range tab {
+		ino, err := strconv.ParseUint(tab[i].ino, 10, 64)
+		if err != nil {
+			continue
+		}
+		if p, ok := r[ino]; ok {
+			tab[i].Process = p
+		}
+	}
+}
+
+// AttachProcesses sets the Process of entries read without looking up their
+// owners, resolving only the inodes of those entries with ResolveInodes.
+// Entries whose socket no inspectable process owns are left untouched. If
+// ctx is done before the walk ends, the processes found so far are attached
+// and ctx.Err() is returned.
+func AttachProcesses(ctx context.Context, entries []SockTabEntry) error {
+	var inodes []uint64
+	for i := range entries {
+		ino, err := strconv.ParseUint(entries[i].ino, 10, 64)
+		if err != nil || ino == 0 {
+			continue
+		}
+		inodes = append(inodes, ino)
+	}
+	res, err := ResolveInodes(ctx, inodes)
+	res.Attach(entries)
+	return err
+}
@@ -0,0 +1,197 @@
+package netstat
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// mockProcess is a process of a mocked /proc: its name and the link targets
+// of its file descriptors
+type mockProcess struct {
+	name  string
+	links []string
+}
+
+// mockProc builds a /proc holding procs, keyed by pid, and points procDir at
+// it
+func mockProc(t *testing.T, procs map[string]mockProcess) (cleanup func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pid, p := range procs {
+		fddir := filepath.Join(dir, pid, "fd")
+		if err := os.MkdirAll(fddir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		stat := pid + " (" + p.name + ") S 1 1 1 0 -1"
+		if err := ioutil.WriteFile(filepath.Join(dir, pid, "stat"), []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for i, l := range p.links {
+			if err := os.Symlink(l, filepath.Join(fddir, strconv.Itoa(3+i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	old := procDir
+	procDir = dir
+	return func() {
+		procDir = old
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAttachProcesses(t *testing.T) {
+	defer mockProc(t, map[string]mockProcess{
+		"10":   {"sshd", []string{"/dev/null", "socket:[100]"}},
+		"20":   {"nginx", []string{"pipe:[5]", "socket:[300]", "socket:[999]"}},
+		"self": {"ignored", []string{"socket:[200]"}},
+	})()
+
+	entries := []SockTabEntry{{ino: "100"}, {ino: "200"}, {ino: "300"}, {ino: "0"}}
+	if err := AttachProcesses(context.Background(), entries); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		pid  int
+		name string
+	}{{10, "sshd"}, {}, {20, "nginx"}, {}}
+	for i, w := range want {
+		p := entries[i].Process
+		switch {
+		case w.pid == 0 && p != nil:
+			t.Errorf("inode %s: got %v, want no process", entries[i].ino, p)
+		case w.pid != 0 && (p == nil || p.Pid != w.pid || p.Name != w.name):
+			t.Errorf("inode %s: got %v, want %d/%s", entries[i].ino, p, w.pid, w.name)
+		}
+	}
+}
+
+func TestAttachProcessesCanceled(t *testing.T) {
+	defer mockProc(t, map[string]mockProcess{
+		"10": {"sshd", []string{"socket:[100]"}},
+	})()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	entries := []SockTabEntry{{ino: "100"}}
+	if err := AttachProcesses(ctx, entries); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	if entries[0].Process != nil {
+		t.Errorf("got %v, want no process once canceled", entries[0].Process)
+	}
+}
+
+// writeProcFile writes the file name of process pid in the mocked /proc
+func writeProcFile(t *testing.T, pid, name, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(procDir, pid, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCgroupPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		want   string
+	}{
+		{
+			name: "v1",
+			cgroup: "12:pids:/user.slice/user-1000.slice/session-2.scope\n" +
+				"11:memory:/user.slice/user-1000.slice\n" +
+				"1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n",
+			want: "/user.slice/user-1000.slice/session-2.scope",
+		},
+		{
+			name:   "v2",
+			cgroup: "0::/system.slice/nginx.service\n",
+			want:   "/system.slice/nginx.service",
+		},
+		{
+			name: "hybrid",
+			cgroup: "12:pids:/system.slice/containerd.service\n" +
+				"1:name=systemd:/system.slice/containerd.service\n" +
+				"0::/kubepods/besteffort/pod1234/cri-containerd-abc.scope\n",
+			want: "/kubepods/besteffort/pod1234/cri-containerd-abc.scope",
+		},
+	}
+	defer mockProc(t, map[string]mockProcess{"10": {name: "app"}})()
+	e := SockTabEntry{Process: &Process{Pid: 10, Name: "app"}}
+	for _, tt := range tests {
+		writeProcFile(t, "10", "cgroup", tt.cgroup)
+		got, err := e.CgroupPath()
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	writeProcFile(t, "10", "cgroup", "")
+	if _, err := e.CgroupPath(); err == nil {
+		t.Error("got no error for an empty cgroup file")
+	}
+	e.Process = &Process{Pid: 99}
+	if _, err := e.CgroupPath(); !os.IsNotExist(err) {
+		t.Errorf("got %v for a process gone, want a missing file error", err)
+	}
+	e.Process = nil
+	if _, err := e.CgroupPath(); err != ErrNoProcess {
+		t.Errorf("got %v without process, want ErrNoProcess", err)
+	}
+}
+
+func TestLoadExe(t *testing.T) {
+	defer mockProc(t, map[string]mockProcess{
+		"10": {name: "nginx"},
+		"20": {name: "kworker"},
+	})()
+	if err := os.Symlink("/usr/sbin/nginx", filepath.Join(procDir, "10", "exe")); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Process{Pid: 10, Name: "nginx"}
+	if err := p.LoadExe(); err != nil || p.Exe != "/usr/sbin/nginx" {
+		t.Errorf("got %q, %v, want /usr/sbin/nginx", p.Exe, err)
+	}
+	// Kernel threads, and processes we may not inspect, have no readable link
+	p = &Process{Pid: 20, Name: "kworker"}
+	if err := p.LoadExe(); err == nil || p.Exe != "" {
+		t.Errorf("got %q, %v, want an error and no executable", p.Exe, err)
+	}
+}
+
+func TestResolveInodesUnreadableProcess(t *testing.T) {
+	defer mockProc(t, map[string]mockProcess{
+		"10": {"sshd", []string{"socket:[100]"}},
+		"20": {"gone", []string{"socket:[200]"}},
+	})()
+	// The process exits between listing its fds and reading its stat
+	if err := os.Remove(filepath.Join(procDir, "20", "stat")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ResolveInodes(context.Background(), []uint64{100, 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := res[100]; p == nil || p.Pid != 10 || p.Name != "sshd" {
+		t.Errorf("inode 100: got %v, want 10/sshd", p)
+	}
+	if p, ok := res[200]; ok {
+		t.Errorf("inode 200: got %v, want no process", p)
+	}
+
+	// extractProcInfo skips the process the same way
+	tab := []SockTabEntry{{ino: "100"}, {ino: "200"}}
+	extractProcInfo(tab)
+	if tab[0].Process == nil || tab[1].Process != nil {
+		t.Errorf("got processes %v and %v, want 10/sshd and none", tab[0].Process, tab[1].Process)
+	}
+}
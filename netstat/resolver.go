@@ -0,0 +1,141 @@
+package netstat
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default HostResolver settings, used when the corresponding field is zero
+const (
+	DefaultResolveTTL         = 5 * time.Minute
+	DefaultResolveNegativeTTL = 30 * time.Second
+	DefaultResolveTimeout     = 2 * time.Second
+	DefaultResolveConcurrency = 8
+)
+
+// HostResolver performs reverse DNS lookups of socket addresses and caches
+// the results. Successful lookups are cached for TTL and addresses without a
+// name (NXDOMAIN) for NegativeTTL. At most MaxConcurrent lookups run at any
+// time, each bounded by Timeout. The zero value is ready to use with the
+// defaults above. A HostResolver is safe for concurrent use.
+type HostResolver struct {
+	TTL           time.Duration
+	NegativeTTL   time.Duration
+	Timeout       time.Duration
+	MaxConcurrent int
+	// Resolver is used for the lookups, net.DefaultResolver if nil
+	Resolver *net.Resolver
+	// Now returns the current time for cache expiry, time.Now if nil. It
+	// allows tests to control the clock.
+	Now func() time.Time
+
+	once     sync.Once
+	sem      chan struct{}
+	mu       sync.Mutex
+	cache    map[string]hostEntry
+	inflight map[string]chan struct{}
+}
+
+type hostEntry struct {
+	name    string
+	expires time.Time
+}
+
+func (r *HostResolver) init() {
+	n := r.MaxConcurrent
+	if n <= 0 {
+		n = DefaultResolveConcurrency
+	}
+	r.sem = make(chan struct{}, n)
+	r.cache = make(map[string]hostEntry)
+	r.inflight = make(map[string]chan struct{})
+}
+
+func (r *HostResolver) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// Resolve returns the host name of ip. The boolean result reports whether a
+// name was found; it is false for addresses without a PTR record, on lookup
+// failure and when ctx is done.
+func (r *HostResolver) Resolve(ctx context.Context, ip net.IP) (string, bool) {
+	r.once.Do(r.init)
+	addr := ip.String()
+
+	for {
+		r.mu.Lock()
+		if e, ok := r.cache[addr]; ok && r.now().Before(e.expires) {
+			r.mu.Unlock()
+			return e.name, e.name != ""
+		}
+		wait, busy := r.inflight[addr]
+		if !busy {
+			wait = make(chan struct{})
+			r.inflight[addr] = wait
+			r.mu.Unlock()
+			break
+		}
+		r.mu.Unlock()
+		// Somebody else is looking the address up, reuse the result
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return "", false
+		}
+	}
+
+	name, ttl, err := r.lookup(ctx, addr)
+	r.mu.Lock()
+	if err == nil {
+		r.cache[addr] = hostEntry{name: name, expires: r.now().Add(ttl)}
+	}
+	close(r.inflight[addr])
+	delete(r.inflight, addr)
+	r.mu.Unlock()
+	return name, name != ""
+}
+
+// lookup resolves addr and returns the name along with how long it may be
+// cached. Transient failures are returned as errors and not cached.
+func (r *HostResolver) lookup(ctx context.Context, addr string) (string, time.Duration, error) {
+	select {
+	case r.sem <- struct{}{}:
+		defer func() { <-r.sem }()
+	case <-ctx.Done():
+		return "", 0, ctx.Err()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, durationOr(r.Timeout, DefaultResolveTimeout))
+	defer cancel()
+
+	res := r.Resolver
+	if res == nil {
+		res = net.DefaultResolver
+	}
+	names, err := res.LookupAddr(ctx, addr)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return "", durationOr(r.NegativeTTL, DefaultResolveNegativeTTL), nil
+		}
+		return "", 0, err
+	}
+	if len(names) == 0 {
+		return "", durationOr(r.NegativeTTL, DefaultResolveNegativeTTL), nil
+	}
+	return strings.TrimSuffix(names[0], "."), durationOr(r.TTL, DefaultResolveTTL), nil
+}
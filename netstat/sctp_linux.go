@@ -0,0 +1,246 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	pathSCTPEps    = "/proc/net/sctp/eps"
+	pathSCTPAssocs = "/proc/net/sctp/assocs"
+
+	// sctpEpsFieldCount and sctpAssocsFieldCount are the number of columns
+	// of /proc/net/sctp/{eps,assocs} before the local addresses
+	sctpEpsFieldCount    = 8
+	sctpAssocsFieldCount = 13
+)
+
+// Association states of the ST column of /proc/net/sctp/assocs, see enum
+// sctp_state
+var sctpAssocStates = [...]SkState{
+	0: Close,       // CLOSED
+	1: SynSent,     // COOKIE_WAIT
+	2: SynSent,     // COOKIE_ECHOED
+	3: Established, // ESTABLISHED
+	4: FinWait1,    // SHUTDOWN_PENDING
+	5: FinWait1,    // SHUTDOWN_SENT
+	6: CloseWait,   // SHUTDOWN_RECEIVED
+	7: LastAck,     // SHUTDOWN_ACK_SENT
+}
+
+// SCTP socket styles of the STY column, see enum sctp_socket_type
+const (
+	sctpSocketUDP              = 0
+	sctpSocketUDPHighBandwidth = 1
+	sctpSocketTCP              = 2
+)
+
+// sctpSockType maps the SCTP socket style of the STY column to SockType: a
+// one-to-many (UDP-style) socket is a SOCK_SEQPACKET, a one-to-one
+// (TCP-style) socket a SOCK_STREAM
+func sctpSockType(t uint64) SockType {
+	switch t {
+	case sctpSocketUDP, sctpSocketUDPHighBandwidth:
+		return SockSeqPacket
+	case sctpSocketTCP:
+		return SockStream
+	}
+	return 0
+}
+
+// parseSCTPAddrs parses a list of SCTP addresses, the primary one marked
+// with a *, and returns the primary address followed by the others. Parsing
+// stops at the first field that is not an address.
+func parseSCTPAddrs(fields []string) (ips []net.IP, rest []string, err error) {
+	for i, f := range fields {
+		primary := strings.HasPrefix(f, "*")
+		f = strings.TrimPrefix(f, "*")
+		if !strings.ContainsAny(f, ".:") {
+			return ips, fields[i:], nil
+		}
+		ip := net.ParseIP(f)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("%w: bad address: %v", ErrParse, f)
+		}
+		if ip4 := ip.To4(); ip4 != nil && !strings.Contains(f, ":") {
+			ip = ip4
+		}
+		if primary {
+			ips = append([]net.IP{ip}, ips...)
+		} else {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil, nil
+}
+
+// sctpAddr returns the primary address of ips with port, the unspecified
+// address of the family of other if ips is empty
+func sctpAddr(ips []net.IP, port uint16, other net.IP) *SockAddr {
+	if len(ips) != 0 {
+		return &SockAddr{IP: ips[0], Port: port}
+	}
+	if other.To4() != nil {
+		return &SockAddr{IP: net.IPv4zero.To4(), Port: port}
+	}
+	return &SockAddr{IP: net.IPv6unspecified, Port: port}
+}
+
+func parseSCTPEpsLine(fields []string) (SockTabEntry, error) {
+	var e SockTabEntry
+	if len(fields) < sctpEpsFieldCount+1 {
+		return e, fmt.Errorf("%w: %v, %v", ErrNotEnoughFields, len(fields), fields)
+	}
+	// ENDPT SOCK STY SST HBKT LPORT UID INODE LADDRS
+	typ, err := strconv.ParseUint(fields[2], 10, 16)
+	if err != nil {
+		return e, err
+	}
+	e.Type = sctpSockType(typ)
+	st, err := strconv.ParseUint(fields[3], 10, 8)
+	if err != nil {
+		return e, err
+	}
+	e.State = SkState(st)
+	port, err := strconv.ParseUint(fields[5], 10, 16)
+	if err != nil {
+		return e, err
+	}
+	u, err := strconv.ParseUint(fields[6], 10, 32)
+	if err != nil {
+		return e, fmt.Errorf("%w: %q", ErrBadUID, fields[6])
+	}
+	e.UID = uint32(u)
+	e.ino = fields[7]
+	ips, _, err := parseSCTPAddrs(fields[sctpEpsFieldCount:])
+	if err != nil {
+		return e, err
+	}
+	if len(ips) == 0 {
+		return e, fmt.Errorf("%w: no local address", ErrParse)
+	}
+	e.LocalAddr = sctpAddr(ips, uint16(port), nil)
+	if len(ips) > 1 {
+		e.ExtraLocalIPs = ips[1:]
+	}
+	e.RemoteAddr = sctpAddr(nil, 0, ips[0])
+	return e, nil
+}
+
+func parseSCTPAssocsLine(fields []string) (SockTabEntry, error) {
+	var e SockTabEntry
+	if len(fields) < sctpAssocsFieldCount+1 {
+		return e, fmt.Errorf("%w: %v, %v", ErrNotEnoughFields, len(fields), fields)
+	}
+	// ASSOC SOCK STY SST ST HBKT ASSOC-ID TX_QUEUE RX_QUEUE UID INODE LPORT
+	// RPORT LADDRS <-> RADDRS HBINT INS OUTS ...
+	typ, err := strconv.ParseUint(fields[2], 10, 16)
+	if err != nil {
+		return e, err
+	}
+	e.Type = sctpSockType(typ)
+	st, err := strconv.ParseUint(fields[4], 10, 8)
+	if err != nil {
+		return e, err
+	}
+	if int(st) < len(sctpAssocStates) {
+		e.State = sctpAssocStates[st]
+	}
+	e.TxQueue, err = strconv.ParseUint(fields[7], 10, 64)
+	if err != nil {
+		return e, err
+	}
+	e.RxQueue, err = strconv.ParseUint(fields[8], 10, 64)
+	if err != nil {
+		return e, err
+	}
+	u, err := strconv.ParseUint(fields[9], 10, 32)
+	if err != nil {
+		return e, fmt.Errorf("%w: %q", ErrBadUID, fields[9])
+	}
+	e.UID = uint32(u)
+	e.ino = fields[10]
+	lport, err := strconv.ParseUint(fields[11], 10, 16)
+	if err != nil {
+		return e, err
+	}
+	rport, err := strconv.ParseUint(fields[12], 10, 16)
+	if err != nil {
+		return e, err
+	}
+	local, rest, err := parseSCTPAddrs(fields[sctpAssocsFieldCount:])
+	if err != nil {
+		return e, err
+	}
+	if len(local) == 0 || len(rest) == 0 || rest[0] != "<->" {
+		return e, fmt.Errorf("%w: bad address list: %v", ErrParse, fields[sctpAssocsFieldCount:])
+	}
+	remote, _, err := parseSCTPAddrs(rest[1:])
+	if err != nil {
+		return e, err
+	}
+	e.LocalAddr = sctpAddr(local, uint16(lport), nil)
+	if len(local) > 1 {
+		e.ExtraLocalIPs = local[1:]
+	}
+	e.RemoteAddr = sctpAddr(remote, uint16(rport), local[0])
+	if len(remote) > 1 {
+		e.ExtraRemoteIPs = remote[1:]
+	}
+	return e, nil
+}
+
+// sctpTabParser returns a tabParser for the SCTP table whose lines are
+// parsed by parseLine
+func sctpTabParser(parseLine func([]string) (SockTabEntry, error)) tabParser {
+	return func(r io.Reader, _ string, accept AcceptFn) ([]SockTabEntry, error) {
+		br := bufio.NewScanner(r)
+		tab := make([]SockTabEntry, 0, 4)
+
+		// Discard title
+		br.Scan()
+
+		for n := 2; br.Scan(); n++ {
+			line := br.Text()
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			e, err := parseLine(fields)
+			if err != nil {
+				return nil, newParseError(n, line, err)
+			}
+			e.Transport = "sctp"
+			e.Protocol = protoSCTP
+			if accept(&e) {
+				tab = append(tab, e)
+			}
+		}
+		return tab, br.Err()
+	}
+}
+
+// SCTPSocks returns a slice of SCTP endpoints and associations, for both IP
+// versions, containing only those elements that satisfy the accept function.
+// Endpoints come first, from /proc/net/sctp/eps: every bound socket, in the
+// Listen state if it accepts associations, with an unspecified remote
+// address. They are followed by the associations from /proc/net/sctp/assocs,
+// whose state is mapped from the SCTP association state onto the closest TCP
+// state. A multihomed endpoint lists its primary address in LocalAddr (or
+// RemoteAddr) and the others in ExtraLocalIPs (or ExtraRemoteIPs). The error
+// wraps os.ErrNotExist when the sctp module is not loaded.
+func SCTPSocks(accept AcceptFn) ([]SockTabEntry, error) {
+	eps, err := doNetstat(pathSCTPEps, sctpTabParser(parseSCTPEpsLine), accept)
+	if err != nil {
+		return nil, err
+	}
+	assocs, err := doNetstat(pathSCTPAssocs, sctpTabParser(parseSCTPAssocsLine), accept)
+	if err != nil {
+		return nil, err
+	}
+	return append(eps, assocs...), nil
+}
@@ -0,0 +1,172 @@
+package netstat
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	sctpEpsHeader    = " ENDPT     SOCK   STY SST HBKT LPORT   UID INODE LADDRS\n"
+	sctpAssocsHeader = " ASSOC     SOCK   STY SST ST HBKT ASSOC-ID TX_QUEUE RX_QUEUE UID INODE LPORT RPORT LADDRS <-> RADDRS HBINT INS OUTS MAXRT T1X T2X RTXC wmema wmemq sndbuf rcvbuf\n"
+)
+
+// sctpAssocLine returns an /proc/net/sctp/assocs line of an association
+// between 127.0.0.1:5001 and 127.0.0.1:40654 in state st
+func sctpAssocLine(st string) string {
+	return "ffff9a0c4a3e1000 ffff9a0c4b5e8400 2   1   " + st + "  29  1        0        0       0 130529 5001  40654  127.0.0.1 <-> *127.0.0.1 \t    7500    10    10   10    0    0        0        1        0   212992   212992"
+}
+
+func TestParseSCTPAssocStates(t *testing.T) {
+	tests := []struct {
+		st   string
+		want SkState
+	}{
+		{"0", Close},       // CLOSED
+		{"1", SynSent},     // COOKIE_WAIT
+		{"2", SynSent},     // COOKIE_ECHOED
+		{"3", Established}, // ESTABLISHED
+		{"4", FinWait1},    // SHUTDOWN_PENDING
+		{"5", FinWait1},    // SHUTDOWN_SENT
+		{"6", CloseWait},   // SHUTDOWN_RECEIVED
+		{"7", LastAck},     // SHUTDOWN_ACK_SENT
+	}
+	for _, tt := range tests {
+		tab, err := ParseProcNet(strings.NewReader(sctpAssocsHeader+sctpAssocLine(tt.st)), "sctp/assocs", NoopFilter)
+		if err != nil {
+			t.Errorf("state %s: %v", tt.st, err)
+			continue
+		}
+		if len(tab) != 1 || tab[0].State != tt.want {
+			t.Errorf("state %s: got %v, want %v", tt.st, tab, tt.want)
+		}
+	}
+}
+
+func ips(s ...string) []net.IP {
+	var l []net.IP
+	for _, a := range s {
+		l = append(l, net.ParseIP(a))
+	}
+	return l
+}
+
+func TestParseProcNetSCTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		table       string
+		line        string
+		local       string
+		remote      string
+		state       SkState
+		typ         SockType
+		extraLocal  []net.IP
+		extraRemote []net.IP
+		ino         string
+	}{
+		{
+			name:       "multihomed IPv4 listener",
+			table:      "sctp/eps",
+			line:       "ffff88017e0a0200 ffff880299f7fa00 2   10  29   5001      0 15367 10.0.0.1 10.0.1.1 ",
+			local:      "10.0.0.1:5001",
+			remote:     "0.0.0.0:0",
+			state:      Listen,
+			typ:        SockStream,
+			extraLocal: ips("10.0.1.1"),
+			ino:        "15367",
+		},
+		{
+			name:       "multihomed IPv6 endpoint",
+			table:      "sctp/eps",
+			line:       "ffff88017e0a0400 ffff880299f7fc00 0   7   29   9899   1000 15412 0000:0000:0000:0000:0000:0000:0000:0001 fd00:0000:0000:0000:0000:0000:0000:0002 ",
+			local:      "[::1]:9899",
+			remote:     "[::]:0",
+			state:      Close,
+			typ:        SockSeqPacket,
+			extraLocal: ips("fd00::2"),
+			ino:        "15412",
+		},
+		{
+			name:        "multihomed IPv4 association, primary path second",
+			table:       "sctp/assocs",
+			line:        "ffff8800b4e1c000 ffff8800b9f0c780 2   1   3  43452    2        0        0       0 11357 5001  42162  10.0.0.1 *10.0.1.1 <-> 10.0.0.2 *10.0.1.2 \t    7500    10    10    2    0    0        0        1        0   212992   212992",
+			local:       "10.0.1.1:5001",
+			remote:      "10.0.1.2:42162",
+			state:       Established,
+			typ:         SockStream,
+			extraLocal:  ips("10.0.0.1"),
+			extraRemote: ips("10.0.0.2"),
+			ino:         "11357",
+		},
+		{
+			name:        "multihomed IPv6 association",
+			table:       "sctp/assocs",
+			line:        "ffff8800b4e1d000 ffff8800b9f0d780 0   1   3  43453    3        0        0    1000 11402 9899  36012  *fd00:0000:0000:0000:0000:0000:0000:0001 fd01:0000:0000:0000:0000:0000:0000:0001 <-> *fd00:0000:0000:0000:0000:0000:0000:0002 fd01:0000:0000:0000:0000:0000:0000:0002 \t    7500    10    10    2    0    0        0        1        0   212992   212992",
+			local:       "[fd00::1]:9899",
+			remote:      "[fd00::2]:36012",
+			state:       Established,
+			typ:         SockSeqPacket,
+			extraLocal:  ips("fd01::1"),
+			extraRemote: ips("fd01::2"),
+			ino:         "11402",
+		},
+	}
+	header := map[string]string{"sctp/eps": sctpEpsHeader, "sctp/assocs": sctpAssocsHeader}
+	for _, tt := range tests {
+		tab, err := ParseProcNet(strings.NewReader(header[tt.table]+tt.line), tt.table, NoopFilter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(tab) != 1 {
+			t.Errorf("%s: got %d entries, want 1", tt.name, len(tab))
+			continue
+		}
+		e := tab[0]
+		local := net.JoinHostPort(e.LocalAddr.IP.String(), strconv.Itoa(int(e.LocalAddr.Port)))
+		remote := net.JoinHostPort(e.RemoteAddr.IP.String(), strconv.Itoa(int(e.RemoteAddr.Port)))
+		if local != tt.local || remote != tt.remote {
+			t.Errorf("%s: got %s <-> %s, want %s <-> %s", tt.name, local, remote, tt.local, tt.remote)
+		}
+		if e.Type != tt.typ {
+			t.Errorf("%s: got type %v, want %v", tt.name, e.Type, tt.typ)
+		}
+		if e.State != tt.state || e.ino != tt.ino || e.Transport != "sctp" || e.Protocol != protoSCTP {
+			t.Errorf("%s: got state %v inode %s transport %s protocol %d, want %v %s sctp %d",
+				tt.name, e.State, e.ino, e.Transport, e.Protocol, tt.state, tt.ino, protoSCTP)
+		}
+		if !equalIPs(e.ExtraLocalIPs, tt.extraLocal) || !equalIPs(e.ExtraRemoteIPs, tt.extraRemote) {
+			t.Errorf("%s: got extra addresses %v <-> %v, want %v <-> %v",
+				tt.name, e.ExtraLocalIPs, e.ExtraRemoteIPs, tt.extraLocal, tt.extraRemote)
+		}
+	}
+}
+
+func TestParseProcNetSCTPMissingSeparator(t *testing.T) {
+	line := "ffff8800b4e1c000 ffff8800b9f0c780 2   1   3  43452    2        0        0       0 11357 5001  42162  10.0.0.1 10.0.0.2 \t    7500"
+	_, err := ParseProcNet(strings.NewReader(sctpAssocsHeader+line), "sctp/assocs", NoopFilter)
+	if pe, ok := err.(*ParseError); !ok || pe.Line != 2 {
+		t.Errorf("got %v, want a *ParseError for line 2", err)
+	}
+}
+
+func equalIPs(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSCTPSockType(t *testing.T) {
+	for sty, want := range []SockType{SockSeqPacket, SockSeqPacket, SockStream, 0} {
+		if got := sctpSockType(uint64(sty)); got != want {
+			t.Errorf("style %d: got %v, want %v", sty, got, want)
+		}
+	}
+}
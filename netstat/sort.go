@@ -0,0 +1,74 @@
+package netstat
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortKey selects the field SortEntries orders by
+type SortKey uint8
+
+// Sort keys
+const (
+	SortByTransport SortKey = iota + 1
+	SortByLocalPort
+	SortByRemotePort
+	SortByState
+	SortByPID
+)
+
+func (k SortKey) String() string {
+	switch k {
+	case SortByTransport:
+		return "transport"
+	case SortByLocalPort:
+		return "local-port"
+	case SortByRemotePort:
+		return "remote-port"
+	case SortByState:
+		return "state"
+	case SortByPID:
+		return "pid"
+	}
+	return fmt.Sprintf("SortKey(%d)", k)
+}
+
+func addrPort(a *SockAddr) int {
+	if a == nil {
+		return -1
+	}
+	return int(a.Port)
+}
+
+func entryPID(e *SockTabEntry) int {
+	if e.Process == nil {
+		return -1
+	}
+	return e.Process.Pid
+}
+
+// SortEntries sorts entries in place in ascending order of the field
+// selected by by. The sort is stable, so entries with equal keys keep their
+// relative order and the result is reproducible; sort again by a secondary
+// key first to break ties. Entries without the field (no IP endpoint, owner
+// unknown) come first. An unknown key leaves entries untouched.
+func SortEntries(entries []SockTabEntry, by SortKey) {
+	var less func(a, b *SockTabEntry) bool
+	switch by {
+	case SortByTransport:
+		less = func(a, b *SockTabEntry) bool { return a.Transport < b.Transport }
+	case SortByLocalPort:
+		less = func(a, b *SockTabEntry) bool { return addrPort(a.LocalAddr) < addrPort(b.LocalAddr) }
+	case SortByRemotePort:
+		less = func(a, b *SockTabEntry) bool { return addrPort(a.RemoteAddr) < addrPort(b.RemoteAddr) }
+	case SortByState:
+		less = func(a, b *SockTabEntry) bool { return a.State < b.State }
+	case SortByPID:
+		less = func(a, b *SockTabEntry) bool { return entryPID(a) < entryPID(b) }
+	default:
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return less(&entries[i], &entries[j])
+	})
+}
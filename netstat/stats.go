@@ -0,0 +1,296 @@
+package netstat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TopByTxQueue returns up to n entries with the largest send queue, largest
+// first. Entries with equal send queues are ordered by their receive queue.
+// The input slice is left untouched.
+func TopByTxQueue(entries []SockTabEntry, n int) []SockTabEntry {
+	if n <= 0 {
+		return nil
+	}
+	top := make([]SockTabEntry, len(entries))
+	copy(top, entries)
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].TxQueue != top[j].TxQueue {
+			return top[i].TxQueue > top[j].TxQueue
+		}
+		return top[i].RxQueue > top[j].RxQueue
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// StateHistogram counts the sockets returned by socks per state, considering
+// only the entries that satisfy the accept function (nil accepts all). The
+// entries are counted as they are parsed and never collected, which also
+// skips the costly process lookup.
+func StateHistogram(socks SocksFn, accept AcceptFn) (map[SkState]int, error) {
+	hist := make(map[SkState]int)
+	_, err := socks(func(s *SockTabEntry) bool {
+		if accept == nil || accept(s) {
+			hist[s.State]++
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hist, nil
+}
+
+// aggregateSampleRemotes is the number of remote endpoints kept per
+// ServiceAggregate
+const aggregateSampleRemotes = 5
+
+// ServiceAggregate summarizes the established client connections of a
+// service, identified by its local address and owning process
+type ServiceAggregate struct {
+	LocalAddr *SockAddr
+	Process   *Process
+	Count     int
+	// Remotes holds a sample of the clients' endpoints
+	Remotes []*SockAddr
+}
+
+// isServerSide reports whether the local end of the connection e is the
+// service side. A connection belongs to a service if its local port is also
+// listened on; otherwise the endpoint with the lower port is assumed to be
+// the service, as clients use ephemeral ports.
+func isServerSide(e *SockTabEntry, listening map[uint16]bool) bool {
+	if listening[e.LocalAddr.Port] {
+		return true
+	}
+	if listening[e.RemoteAddr.Port] {
+		return false
+	}
+	return e.LocalAddr.Port < e.RemoteAddr.Port
+}
+
+// AggregateClients collapses the established connections accepted by local
+// services into one ServiceAggregate per local address, port and process.
+// Listening sockets in entries help telling the service side of a connection
+// from the client side; outbound connections are not included in the result.
+func AggregateClients(entries []SockTabEntry) []ServiceAggregate {
+	listening := make(map[uint16]bool)
+	for _, e := range entries {
+		if e.State == Listen && e.LocalAddr != nil {
+			listening[e.LocalAddr.Port] = true
+		}
+	}
+
+	type key struct {
+		ip   string
+		port uint16
+		pid  int
+	}
+	var aggs []ServiceAggregate
+	index := make(map[key]int)
+	for _, e := range entries {
+		if e.State != Established || e.LocalAddr == nil || e.RemoteAddr == nil ||
+			!isServerSide(&e, listening) {
+			continue
+		}
+		k := key{ip: e.LocalAddr.IP.String(), port: e.LocalAddr.Port}
+		if e.Process != nil {
+			k.pid = e.Process.Pid
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(aggs)
+			index[k] = i
+			aggs = append(aggs, ServiceAggregate{
+				LocalAddr: e.LocalAddr,
+				Process:   e.Process,
+			})
+		}
+		a := &aggs[i]
+		a.Count++
+		if len(a.Remotes) < aggregateSampleRemotes {
+			a.Remotes = append(a.Remotes, e.RemoteAddr)
+		}
+	}
+	return aggs
+}
+
+// LocalPortUsage counts the connections using each local port. Listening
+// sockets are not connections and are not counted. For outbound connections
+// this shows how much the ephemeral port range is used.
+func LocalPortUsage(entries []SockTabEntry) map[uint16]int {
+	usage := make(map[uint16]int)
+	for _, e := range entries {
+		if e.State != Listen && e.LocalAddr != nil {
+			usage[e.LocalAddr.Port]++
+		}
+	}
+	return usage
+}
+
+// ExposureClass tells on which addresses a service accepts traffic. Classes
+// are ordered from the least to the most exposed.
+type ExposureClass uint8
+
+// Exposure classes
+const (
+	LoopbackOnly ExposureClass = iota + 1
+	SpecificAddress
+	AllInterfaces
+)
+
+func (c ExposureClass) String() string {
+	switch c {
+	case LoopbackOnly:
+		return "loopback-only"
+	case SpecificAddress:
+		return "specific-address"
+	case AllInterfaces:
+		return "all-interfaces"
+	}
+	return fmt.Sprintf("ExposureClass(%d)", c)
+}
+
+// ExposureSummary classifies the exposure of every listening TCP socket and
+// unconnected UDP socket in entries. Results are keyed by "proto:port:process"
+// where proto is tcp or udp for either IP version and process is the owning
+// process name, empty if unknown. A service bound to several addresses gets
+// the class of its most exposed bind.
+func ExposureSummary(entries []SockTabEntry) map[string]ExposureClass {
+	sum := make(map[string]ExposureClass)
+	for _, e := range entries {
+		if e.LocalAddr == nil {
+			continue
+		}
+		proto := strings.TrimSuffix(e.Transport, "6")
+		switch proto {
+		case "tcp":
+			if e.State != Listen {
+				continue
+			}
+		case "udp":
+			if e.RemoteAddr == nil || e.RemoteAddr.Port != 0 {
+				continue
+			}
+		default:
+			continue
+		}
+
+		var class ExposureClass
+		switch ip := e.LocalAddr.IP; {
+		case ip.IsUnspecified():
+			class = AllInterfaces
+		case ip.IsLoopback():
+			class = LoopbackOnly
+		default:
+			class = SpecificAddress
+		}
+		name := ""
+		if e.Process != nil {
+			name = e.Process.Name
+		}
+		key := fmt.Sprintf("%s:%d:%s", proto, e.LocalAddr.Port, name)
+		if class > sum[key] {
+			sum[key] = class
+		}
+	}
+	return sum
+}
+
+// SummaryStats holds socket counts per transport and per state, much like the
+// socket section of netstat -s
+type SummaryStats struct {
+	Total int
+	// ByTransport counts the sockets of each transport (tcp, tcp6, ...)
+	ByTransport map[string]int
+	// ByState counts the sockets of each transport per state
+	ByState map[string]map[SkState]int
+}
+
+// Summary counts entries by transport and state
+func Summary(entries []SockTabEntry) SummaryStats {
+	s := SummaryStats{
+		Total:       len(entries),
+		ByTransport: make(map[string]int),
+		ByState:     make(map[string]map[SkState]int),
+	}
+	for _, e := range entries {
+		s.ByTransport[e.Transport]++
+		states := s.ByState[e.Transport]
+		if states == nil {
+			states = make(map[SkState]int)
+			s.ByState[e.Transport] = states
+		}
+		states[e.State]++
+	}
+	return s
+}
+
+// String renders the counts as a block per transport, transports and states
+// in a stable order:
+//
+//	tcp:
+//	    3 sockets
+//	    2 ESTABLISHED
+//	    1 LISTEN
+func (s SummaryStats) String() string {
+	transports := make([]string, 0, len(s.ByTransport))
+	for t := range s.ByTransport {
+		transports = append(transports, t)
+	}
+	sort.Strings(transports)
+
+	var b strings.Builder
+	for _, t := range transports {
+		fmt.Fprintf(&b, "%s:\n    %d sockets\n", t, s.ByTransport[t])
+		states := make([]SkState, 0, len(s.ByState[t]))
+		for st := range s.ByState[t] {
+			states = append(states, st)
+		}
+		sort.Slice(states, func(i, j int) bool { return states[i] < states[j] })
+		for _, st := range states {
+			fmt.Fprintf(&b, "    %d %v\n", s.ByState[t][st], st)
+		}
+	}
+	return b.String()
+}
+
+// QueueStat sums the queues of a group of sockets
+type QueueStat struct {
+	TxQueue uint64
+	RxQueue uint64
+	Count   int
+}
+
+// QueueTotals returns the sum of the send and receive queues of entries
+func QueueTotals(entries []SockTabEntry) (tx, rx uint64) {
+	for _, e := range entries {
+		tx += e.TxQueue
+		rx += e.RxQueue
+	}
+	return tx, rx
+}
+
+// QueueByProcess sums the send and receive queues of entries per owning
+// process, keyed by pid. Sockets whose owner is unknown are counted under
+// pid 0. Note that the queues of listening TCP sockets hold their accept
+// backlog rather than bytes.
+func QueueByProcess(entries []SockTabEntry) map[int]QueueStat {
+	stats := make(map[int]QueueStat)
+	for _, e := range entries {
+		pid := 0
+		if e.Process != nil {
+			pid = e.Process.Pid
+		}
+		s := stats[pid]
+		s.TxQueue += e.TxQueue
+		s.RxQueue += e.RxQueue
+		s.Count++
+		stats[pid] = s
+	}
+	return stats
+}
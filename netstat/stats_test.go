@@ -0,0 +1,68 @@
+package netstat
+
+import "testing"
+
+func TestQueueTotals(t *testing.T) {
+	nginx := &Process{Pid: 20, Name: "nginx"}
+	entries := []SockTabEntry{
+		{TxQueue: 100, RxQueue: 1, Process: nginx},
+		{TxQueue: 200, RxQueue: 2, Process: nginx},
+		{TxQueue: 10, RxQueue: 30, Process: &Process{Pid: 30, Name: "redis"}},
+		{TxQueue: 5, RxQueue: 7},
+		{RxQueue: 3},
+	}
+	tx, rx := QueueTotals(entries)
+	if tx != 315 || rx != 43 {
+		t.Errorf("got totals %d %d, want 315 43", tx, rx)
+	}
+	if tx, rx := QueueTotals(nil); tx != 0 || rx != 0 {
+		t.Errorf("got totals %d %d for no entries, want 0 0", tx, rx)
+	}
+
+	want := map[int]QueueStat{
+		20: {TxQueue: 300, RxQueue: 3, Count: 2},
+		30: {TxQueue: 10, RxQueue: 30, Count: 1},
+		0:  {TxQueue: 5, RxQueue: 10, Count: 2}, // unknown owner
+	}
+	got := QueueByProcess(entries)
+	if len(got) != len(want) {
+		t.Errorf("got %d processes, want %d: %v", len(got), len(want), got)
+	}
+	for pid, w := range want {
+		if got[pid] != w {
+			t.Errorf("pid %d: got %+v, want %+v", pid, got[pid], w)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	entries := []SockTabEntry{
+		{Transport: "tcp", State: Established},
+		{Transport: "tcp", State: TimeWait},
+		{Transport: "tcp", State: Established},
+		{Transport: "udp", State: Established},
+		{Transport: "tcp6", State: TimeWait},
+	}
+	s := Summary(entries)
+	if s.Total != 5 {
+		t.Errorf("got total %d, want 5", s.Total)
+	}
+	if s.ByTransport["tcp"] != 3 || s.ByTransport["tcp6"] != 1 || s.ByTransport["udp"] != 1 {
+		t.Errorf("got %v per transport, want tcp 3 tcp6 1 udp 1", s.ByTransport)
+	}
+	if tcp := s.ByState["tcp"]; len(tcp) != 2 || tcp[Established] != 2 || tcp[TimeWait] != 1 {
+		t.Errorf("got %v for tcp, want 2 established and 1 in time wait", tcp)
+	}
+
+	// States are listed in the order of their value, which places
+	// ESTABLISHED before TIME_WAIT on every platform
+	want := "tcp:\n    3 sockets\n    2 ESTABLISHED\n    1 TIME_WAIT\n" +
+		"tcp6:\n    1 sockets\n    1 TIME_WAIT\n" +
+		"udp:\n    1 sockets\n    1 ESTABLISHED\n"
+	if got := s.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := Summary(nil).String(); got != "" {
+		t.Errorf("got %q for no entries, want \"\"", got)
+	}
+}
@@ -0,0 +1,121 @@
+package netstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// soAcceptCon is the __SO_ACCEPTCON flag of a listening socket
+	soAcceptCon = 1 << 16
+
+	// Socket states of the St column, see enum socket_state
+	ssConnecting    = 2
+	ssConnected     = 3
+	ssDisconnecting = 4
+)
+
+// unixFieldCount is the number of columns of /proc/net/unix before the path
+const unixFieldCount = 7
+
+// cutFields splits off the first n whitespace separated fields of s and
+// returns them along with the remainder of s
+func cutFields(s string, n int) ([]string, string) {
+	fields := make([]string, 0, n)
+	for len(fields) < n {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			break
+		}
+		i := strings.IndexAny(s, " \t")
+		if i < 0 {
+			i = len(s)
+		}
+		fields = append(fields, s[:i])
+		s = s[i:]
+	}
+	return fields, strings.TrimLeft(s, " \t")
+}
+
+func parseUnixSockTabLine(line string) (SockTabEntry, error) {
+	var e SockTabEntry
+	// The path may contain blanks, so it is everything after the inode
+	fields, p := cutFields(line, unixFieldCount)
+	if len(fields) < unixFieldCount {
+		return e, fmt.Errorf("%w: %v, %v", ErrNotEnoughFields, len(fields), fields)
+	}
+	var err error
+	e.Pointer, err = strconv.ParseUint(strings.TrimSuffix(fields[0], ":"), 16, 64)
+	if err != nil {
+		return e, err
+	}
+	u, err := strconv.ParseUint(fields[1], 16, 32)
+	if err != nil {
+		return e, err
+	}
+	e.Ref = uint32(u)
+	flags, err := strconv.ParseUint(fields[3], 16, 32)
+	if err != nil {
+		return e, err
+	}
+	typ, err := strconv.ParseUint(fields[4], 16, 16)
+	if err != nil {
+		return e, err
+	}
+	e.Type = linuxSockType(typ)
+	st, err := strconv.ParseUint(fields[5], 16, 8)
+	if err != nil {
+		return e, err
+	}
+	switch {
+	case flags&soAcceptCon != 0:
+		e.State = Listen
+	case st == ssConnecting:
+		e.State = SynSent
+	case st == ssConnected:
+		e.State = Established
+	case st == ssDisconnecting:
+		e.State = Closing
+	default:
+		e.State = Close
+	}
+	e.ino = fields[6]
+	e.Path = p
+	return e, nil
+}
+
+func parseUnixSockTab(r io.Reader, transport string, accept AcceptFn) ([]SockTabEntry, error) {
+	br := bufio.NewScanner(r)
+	tab := make([]SockTabEntry, 0, 4)
+
+	// Discard title
+	br.Scan()
+
+	for n := 2; br.Scan(); n++ {
+		line := br.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, err := parseUnixSockTabLine(line)
+		if err != nil {
+			return nil, newParseError(n, line, err)
+		}
+		e.Transport = transport
+		if accept(&e) {
+			tab = append(tab, e)
+		}
+	}
+	return tab, br.Err()
+}
+
+// UnixSocks returns a slice of Unix domain sockets containing only those
+// elements that satisfy the accept function. Unix sockets have no IP
+// endpoints, so LocalAddr and RemoteAddr are nil and Path holds the bound
+// path, if any. Listening sockets are in the Listen state and connected ones
+// Established; UID is not reported by the kernel and stays zero.
+func UnixSocks(accept AcceptFn) ([]SockTabEntry, error) {
+	return doNetstat(pathUnixTab, parseUnixSockTab, accept)
+}
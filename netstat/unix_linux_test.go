@@ -0,0 +1,72 @@
+package netstat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseUnixSockTab(t *testing.T) {
+	const header = "Num       RefCount Protocol Flags    Type St Inode Path\n"
+	tests := []struct {
+		name  string
+		line  string
+		state SkState
+		typ   SockType
+		path  string
+	}{
+		{
+			name:  "listener, path with blanks",
+			line:  "ffff9a0c4a3e1000: 00000002 00000000 00010000 0001 01 30001 /tmp/my socket dir/app.sock",
+			state: Listen,
+			typ:   SockStream,
+			path:  "/tmp/my socket dir/app.sock",
+		},
+		{
+			name:  "connected, abstract name",
+			line:  "ffff9a0c4a3e1400: 00000003 00000000 00000000 0001 03 30002 @/tmp/.X11-unix/X0",
+			state: Established,
+			typ:   SockStream,
+			path:  "@/tmp/.X11-unix/X0",
+		},
+		{
+			name:  "connected, unnamed",
+			line:  "ffff9a0c4a3e1800: 00000003 00000000 00000000 0001 03 30003",
+			state: Established,
+			typ:   SockStream,
+		},
+		{
+			name:  "unconnected datagram",
+			line:  "ffff9a0c4a3e1c00: 00000002 00000000 00000000 0002 01 30004 /run/systemd/notify",
+			state: Close,
+			typ:   SockDgram,
+			path:  "/run/systemd/notify",
+		},
+		{
+			name:  "seqpacket listener, abstract name with a blank",
+			line:  "ffff9a0c4a3e2000: 00000002 00000000 00010000 0005 01 30005 @seq packet",
+			state: Listen,
+			typ:   SockSeqPacket,
+			path:  "@seq packet",
+		},
+	}
+	for _, tt := range tests {
+		tab, err := ParseProcNet(strings.NewReader(header+tt.line+"\n"), "unix", NoopFilter)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(tab) != 1 {
+			t.Errorf("%s: got %d entries, want 1", tt.name, len(tab))
+			continue
+		}
+		e := tab[0]
+		if e.State != tt.state || e.Type != tt.typ || e.Path != tt.path {
+			t.Errorf("%s: got state %v type %v path %q, want %v %v %q",
+				tt.name, e.State, e.Type, e.Path, tt.state, tt.typ, tt.path)
+		}
+		if e.Transport != "unix" || e.LocalAddr != nil || e.RemoteAddr != nil {
+			t.Errorf("%s: got transport %s addresses %v %v, want unix without addresses",
+				tt.name, e.Transport, e.LocalAddr, e.RemoteAddr)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package netstat
+
+import (
+	"errors"
+	"os/user"
+	"strconv"
+	"sync"
+)
+
+var (
+	userMu    sync.Mutex
+	userCache = make(map[uint32]string)
+)
+
+// Username returns the name of the user owning the socket. Lookups are
+// cached per uid for the life of the process. A uid without a passwd entry
+// is not an error: its numeric form is returned, as ls and ps do. Other
+// lookup failures are returned as errors and not cached. Username is safe
+// for concurrent use.
+func (e *SockTabEntry) Username() (string, error) {
+	userMu.Lock()
+	name, ok := userCache[e.UID]
+	userMu.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	id := strconv.FormatUint(uint64(e.UID), 10)
+	u, err := user.LookupId(id)
+	switch {
+	case err == nil:
+		name = u.Username
+	case errors.As(err, new(user.UnknownUserIdError)):
+		name = id
+	default:
+		return "", err
+	}
+
+	userMu.Lock()
+	userCache[e.UID] = name
+	userMu.Unlock()
+	return name, nil
+}
@@ -0,0 +1,152 @@
+package netstat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SockEventType is the kind of change reported by a SockEvent
+type SockEventType uint8
+
+// Socket event types
+const (
+	Added SockEventType = iota + 1
+	Removed
+	StateChanged
+)
+
+var sockEventTypes = [...]string{
+	Added:        "ADDED",
+	Removed:      "REMOVED",
+	StateChanged: "STATE_CHANGED",
+}
+
+func (t SockEventType) String() string {
+	if int(t) >= len(sockEventTypes) || sockEventTypes[t] == "" {
+		return fmt.Sprintf("UNKNOWN(%d)", t)
+	}
+	return sockEventTypes[t]
+}
+
+// SockEvent is a change of the socket tables observed by Watch. For Removed
+// events Entry is the socket as last seen, otherwise the current one.
+type SockEvent struct {
+	Type  SockEventType
+	Entry SockTabEntry
+}
+
+// sockKey identifies a socket across scans
+func sockKey(e *SockTabEntry) string {
+	return fmt.Sprintf("%s %v %v", e.Transport, e.LocalAddr, e.RemoteAddr)
+}
+
+func sockMap(tabs []SockTabEntry) map[string]SockTabEntry {
+	m := make(map[string]SockTabEntry, len(tabs))
+	for _, e := range tabs {
+		m[sockKey(&e)] = e
+	}
+	return m
+}
+
+// watchScan reads the tables in t for Watch. Tables missing on this host are
+// skipped. A table that cannot be read otherwise keeps its entries from prev,
+// so that a failed read does not show up as its sockets going away; the
+// errors of those tables are returned in a MultiError.
+func watchScan(t Transports, accept AcceptFn, prev []SockTabEntry) ([]SockTabEntry, error) {
+	var (
+		tabs []SockTabEntry
+		errs MultiError
+	)
+	for _, ts := range transportSocks {
+		if t&ts.t == 0 {
+			continue
+		}
+		tab, err := ts.socks(accept)
+		switch {
+		case err == nil:
+			tabs = append(tabs, tab...)
+		case errors.Is(err, os.ErrNotExist):
+		default:
+			errs = append(errs, err)
+			for _, e := range prev {
+				if e.Transport == ts.name {
+					tabs = append(tabs, e)
+				}
+			}
+		}
+	}
+	if errs != nil {
+		return tabs, errs
+	}
+	return tabs, nil
+}
+
+// Watch scans the tables in t every interval and sends the differences
+// between successive scans on the returned channel. Sockets are identified
+// by transport, local and remote address; only entries satisfying the accept
+// function are considered. The sockets present at the first scan are sent as
+// Added events. Tables missing on this host, like tcp6 with IPv6 disabled,
+// are left out. An error reading another table at the first scan is returned
+// directly; should a later scan fail to read a table, its sockets are taken
+// as unchanged. The channel is closed once ctx is done.
+func Watch(ctx context.Context, t Transports, interval time.Duration, accept AcceptFn) (<-chan SockEvent, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("gonetstat: non-positive watch interval %v", interval)
+	}
+	tabs, err := watchScan(t, accept, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan SockEvent)
+	go func() {
+		defer close(ch)
+		send := func(typ SockEventType, e SockTabEntry) bool {
+			select {
+			case ch <- SockEvent{Type: typ, Entry: e}:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		prev := make(map[string]SockTabEntry)
+		tick := time.NewTicker(interval)
+		defer tick.Stop()
+		for {
+			cur := sockMap(tabs)
+			for k, e := range cur {
+				old, ok := prev[k]
+				switch {
+				case !ok:
+					if !send(Added, e) {
+						return
+					}
+				case old.State != e.State:
+					if !send(StateChanged, e) {
+						return
+					}
+				}
+			}
+			for k, e := range prev {
+				if _, ok := cur[k]; !ok {
+					if !send(Removed, e) {
+						return
+					}
+				}
+			}
+			prev = cur
+
+			select {
+			case <-tick.C:
+			case <-ctx.Done():
+				return
+			}
+			tabs, _ = watchScan(t, accept, tabs)
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,102 @@
+package netstat
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTable replaces the mocked table name in dir with a tcp table holding
+// lines, in one step so a concurrent scan never sees it half written
+func writeTable(t *testing.T, dir, name string, lines ...string) {
+	t.Helper()
+	s := tcpHeader
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	tmp := filepath.Join(dir, "."+name)
+	if err := ioutil.WriteFile(tmp, []byte(s), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, name)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nextEvents reads n events from ch, failing the test if they take too long
+func nextEvents(t *testing.T, ch <-chan SockEvent, n int) map[SockEventType][]uint16 {
+	t.Helper()
+	got := make(map[SockEventType][]uint16)
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < n; i++ {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				t.Fatalf("channel closed after %d events, want %d", i, n)
+			}
+			got[ev.Type] = append(got[ev.Type], ev.Entry.RemoteAddr.Port)
+		case <-timeout:
+			t.Fatalf("got %d events, want %d: %v", i, n, got)
+		}
+	}
+	return got
+}
+
+func TestWatch(t *testing.T) {
+	dir, cleanup := withProcNet(t)
+	defer cleanup()
+	defer mockProc(t, nil)()
+
+	const (
+		listener = "   0: 00000000:0016 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 2001 1 0000000000000000"
+		conn1    = "   1: 0100007F:0016 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000"
+		conn1Fin = "   1: 0100007F:0016 0100007F:D431 08 00000000:00000000 00:00000000 00000000     0        0 2002 1 0000000000000000"
+		conn2    = "   2: 0100007F:0016 0100007F:D432 01 00000000:00000000 00:00000000 00000000     0        0 2003 1 0000000000000000"
+	)
+	writeTable(t, dir, "tcp", listener, conn1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The other tables are missing and left out
+	ch, err := Watch(ctx, TransportAll, 10*time.Millisecond, NoopFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := nextEvents(t, ch, 2)
+	if len(got[Added]) != 2 {
+		t.Fatalf("got %v at the first scan, want 2 sockets added", got)
+	}
+
+	// conn1 enters CLOSE_WAIT, conn2 shows up and the listener goes away
+	writeTable(t, dir, "tcp", conn1Fin, conn2)
+	got = nextEvents(t, ch, 3)
+	want := map[SockEventType]uint16{Added: 0xd432, Removed: 0, StateChanged: 0xd431}
+	for typ, port := range want {
+		if len(got[typ]) != 1 || got[typ][0] != port {
+			t.Errorf("got %v events for remote ports %v, want one for %d", typ, got[typ], port)
+		}
+	}
+
+	cancel()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			t.Errorf("got %v after cancel, want the channel closed", ev.Type)
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
+
+func TestWatchBadInterval(t *testing.T) {
+	if _, err := Watch(context.Background(), TransportAll, 0, NoopFilter); err == nil {
+		t.Error("got no error for a zero interval")
+	}
+}